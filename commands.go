@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+// command is a subcommand of the CLI with its own flag set
+type command struct {
+	name  string
+	args  string
+	short string
+	// help is printed between the usage line and the flags
+	help string
+	run  func(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int
+}
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "devel"
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name:  "report",
+			args:  "[flags] [file...]",
+			short: "print statistics of keyfreq files (default command)",
+			help: `modes (-mode):
+  all        functions and modes, plus the breakdown for markdown and html
+  modes      calls per major mode
+  functions  calls per function
+  pairs      calls per (mode, function) pair
+  breakdown  the most used functions of every mode
+  sexp       the merged counts in the keyfreq.el format
+
+examples:
+  go-keyfreq report -mode functions -format table -top 20
+  go-keyfreq report -i 'snapshots/*.keyfreq' -by-source -mode modes
+  go-keyfreq report -exclude-func self-insert-command -format html > keyfreq.html`,
+			run: runReport,
+		},
+		{
+			name:  "diff",
+			args:  "[flags] old new",
+			short: "compare two snapshots",
+			help: `examples:
+  go-keyfreq diff -format table last-week.keyfreq ~/.emacs.keyfreq`,
+			run: runDiff,
+		},
+		{
+			name:  "merge",
+			args:  "[flags] [file...]",
+			short: "merge keyfreq files into one in the keyfreq.el format",
+			help: `examples:
+  go-keyfreq merge laptop.keyfreq desktop.keyfreq > merged.keyfreq`,
+			run: runMerge,
+		},
+		{
+			name:  "validate",
+			args:  "[flags] [file...]",
+			short: "check keyfreq files and report every malformed entry",
+			help: `The exit code is 1 if any file has a problem.
+
+examples:
+  go-keyfreq validate ~/.emacs.keyfreq`,
+			run: runValidate,
+		},
+		{
+			name:  "convert",
+			args:  "[flags] [file...]",
+			short: "convert the (mode, function) table of keyfreq files to another format",
+			help: `examples:
+  go-keyfreq convert -format json ~/.emacs.keyfreq`,
+			run: runConvert,
+		},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "usage: go-keyfreq <command> [flags] [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintf(w, "\nRun 'go-keyfreq help <command>' for the flags of a command and\n")
+	fmt.Fprintf(w, "'go-keyfreq -version' for the version.\n")
+}
+
+// Usage prints the help of the command parsing flags to w. A non-empty
+// message is printed first.
+func Usage(w io.Writer, flags *flag.FlagSet, message string) {
+	cmd := findCommand(flags.Name())
+	if message != "" {
+		fmt.Fprintf(w, "go-keyfreq %s: %s\n\n", cmd.name, message)
+	}
+	fmt.Fprintf(w, "usage: go-keyfreq %s %s\n\n%s\n\n", cmd.name, cmd.args, cmd.short)
+	if cmd.help != "" {
+		fmt.Fprintf(w, "%s\n\n", cmd.help)
+	}
+	fmt.Fprintf(w, "flags:\n")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+}
+
+// errVersion is returned by parseFlags if -version is given
+var errVersion = errors.New("version requested")
+
+// parseFlags adds the -version flag that every command has and parses args
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	showVersion := flags.Bool("version", false, "print the version and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *showVersion {
+		return errVersion
+	}
+	return nil
+}
+
+// argsError reports an invalid command line and returns the exit code. -h
+// prints the help and -version the version to stdout and succeed.
+func argsError(flags *flag.FlagSet, stdout, stderr io.Writer, err error) int {
+	switch err {
+	case flag.ErrHelp:
+		Usage(stdout, flags, "")
+		return 0
+	case errVersion:
+		fmt.Fprintf(stdout, "go-keyfreq %s\n", version)
+		return 0
+	}
+	Usage(stderr, flags, err.Error())
+	return 2
+}
+
+// runCommand dispatches args to the subcommand named by the first argument.
+// Without a command name the arguments are passed to report.
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	name := "report"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+	if name == "help" {
+		if len(args) == 0 {
+			printCommands(stdout)
+			return 0
+		}
+		// help for a command is the same as its -h flag
+		name, args = args[0], []string{"-h"}
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n", name)
+		printCommands(stderr)
+		return 2
+	}
+
+	// the errors of the flag package are printed by argsError together with
+	// the help of the command
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.Usage = func() {}
+	return cmd.run(flags, args, stdout, stderr)
+}
+
+func runReport(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int {
+	var opts Opts
+	if err := opts.readArgs(flags, args); err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	return run(opts, stdout, stderr)
+}
+
+// readInputArgs parses the flags of the commands that only read input files
+func (o *Opts) readInputArgs(flags *flag.FlagSet, args []string) error {
+	o.addInputFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	return o.finishInputFlags(flags)
+}
+
+func runMerge(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int {
+	var opts Opts
+	opts.addOutputFlag(flags)
+	if err := opts.readInputArgs(flags, args); err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	opts.mode = SEXP
+	return run(opts, stdout, stderr)
+}
+
+func runConvert(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int {
+	var opts Opts
+	opts.addOutputFlag(flags)
+	outFormat := flags.String("format", "json", "output format. Choose between csv, json, table, markdown and html")
+	if err := opts.readInputArgs(flags, args); err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	format, err := OutFormatParse(*outFormat)
+	if err == nil && format == SVG {
+		err = fmt.Errorf("convert can't write svg")
+	}
+	if err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	opts.mode = PAIRS
+	opts.format = format
+	return run(opts, stdout, stderr)
+}
+
+// runValidate parses every file in recovery mode so that all malformed
+// entries are reported and prints a summary line per file
+func runValidate(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int {
+	var opts Opts
+	if err := opts.readInputArgs(flags, args); err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	filenames, err := expandInputs(opts.inputFilenames)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	filter, err := opts.filter()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	code := 0
+	for _, filename := range filenames {
+		if !validateFile(filename, filter, stdout, stderr) {
+			code = 1
+		}
+	}
+	return code
+}
+
+func validateFile(filename string, filter *keyfreq.Filter, stdout, stderr io.Writer) bool {
+	src, err := readInput(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return false
+	}
+
+	parser := keyfreq.NewParser(bytes.NewReader(src))
+	parser.Recover = true
+	if filter != nil {
+		parser.Filter = filter.Match
+	}
+	stats, err := parser.Parse()
+	printParseErrors(stderr, filename, src, parser.Diagnostics(), err)
+	problems := len(parser.Diagnostics())
+	if err != nil {
+		problems++
+	}
+	if problems > 0 {
+		fmt.Fprintf(stdout, "%s: invalid, %d problems\n", filename, problems)
+		return false
+	}
+	fmt.Fprintf(stdout, "%s: ok, %d entries\n", filename, len(stats.Pairs()))
+	return true
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	first := writeTempFile(t, "(((org-mode . next-line) . 1)\n ((org-mode . forward-word) . 4))")
+	defer os.Remove(first)
+	second := writeTempFile(t, "(((org-mode . next-line) . 2)\n ((c-mode . next-line) . 5))")
+	defer os.Remove(second)
+	broken := writeTempFile(t, "(((org-mode . next-line) . x)\n ((org-mode . next-line) . 3))")
+	defer os.Remove(broken)
+
+	testcases := map[string]struct {
+		args         []string
+		wantedCode   int
+		wantedStdout string
+		// only check that stdout starts with wantedStdout
+		prefix       bool
+		wantedStderr string
+	}{
+		"default report": {
+			args:         []string{"-mode", "modes", "-i", first},
+			wantedStdout: "key,count,percent\norg-mode,5,100.000000\n",
+		},
+		"report": {
+			args:         []string{"report", "-mode", "modes", first},
+			wantedStdout: "key,count,percent\norg-mode,5,100.000000\n",
+		},
+		"merge": {
+			args:         []string{"merge", first, second},
+			wantedStdout: "(((c-mode . next-line) . 5)\n ((org-mode . forward-word) . 4)\n ((org-mode . next-line) . 3))\n",
+		},
+		"convert": {
+			args:         []string{"convert", "-format", "csv", first},
+			wantedStdout: "mode,function,count,percent,mode_percent\norg-mode,forward-word,4,80.000000,80.000000\norg-mode,next-line,1,20.000000,20.000000\n",
+		},
+		"convert svg": {
+			args:         []string{"convert", "-format", "svg", first},
+			wantedCode:   2,
+			wantedStderr: "convert can't write svg",
+		},
+		"validate ok": {
+			args:         []string{"validate", first},
+			wantedStdout: first + ": ok, 2 entries\n",
+		},
+		"validate broken": {
+			args:         []string{"validate", first, broken},
+			wantedCode:   1,
+			wantedStdout: first + ": ok, 2 entries\n" + broken + ": invalid, 1 problems\n",
+			wantedStderr: broken + ":1:28: expected number but got 'x' (entry skipped)",
+		},
+		"help": {
+			args:         []string{"help"},
+			wantedStdout: "usage: go-keyfreq <command>",
+			prefix:       true,
+		},
+		"unknown": {
+			args:         []string{"frobnicate"},
+			wantedCode:   2,
+			wantedStderr: "unknown command 'frobnicate'",
+		},
+		"invalid mode": {
+			args:         []string{"report", "-mode", "bogus"},
+			wantedCode:   2,
+			wantedStderr: "go-keyfreq report: don't know mode 'bogus'",
+		},
+		"report help": {
+			args:         []string{"help", "report"},
+			wantedStdout: "usage: go-keyfreq report [flags] [file...]\n",
+			prefix:       true,
+		},
+		"flag help": {
+			args:         []string{"convert", "-h"},
+			wantedStdout: "usage: go-keyfreq convert [flags] [file...]\n",
+			prefix:       true,
+		},
+		"version": {
+			args:         []string{"-version"},
+			wantedStdout: "go-keyfreq devel\n",
+		},
+		"version after flags": {
+			args:         []string{"report", "-i", first, "-version"},
+			wantedStdout: "go-keyfreq devel\n",
+		},
+		"diff version": {
+			args:         []string{"diff", "--version"},
+			wantedStdout: "go-keyfreq devel\n",
+		},
+		"unknown flag": {
+			args:         []string{"merge", "-mode", "modes"},
+			wantedCode:   2,
+			wantedStderr: "go-keyfreq merge: flag provided but not defined: -mode\n\nusage: go-keyfreq merge",
+		},
+	}
+	for name, tc := range testcases {
+		var stdout, stderr bytes.Buffer
+		code := runCommand(tc.args, &stdout, &stderr)
+		if code != tc.wantedCode {
+			t.Errorf("%s: Got exit code %d but wanted %d: %s", name, code, tc.wantedCode, stderr.String())
+		}
+		if got := stdout.String(); got != tc.wantedStdout && !(tc.prefix && strings.HasPrefix(got, tc.wantedStdout)) {
+			t.Errorf("%s: Got stdout\n%s\nbut wanted\n%s", name, got, tc.wantedStdout)
+		}
+		if !strings.Contains(stderr.String(), tc.wantedStderr) {
+			t.Errorf("%s: Got stderr '%s' but wanted it to contain '%s'", name, stderr.String(), tc.wantedStderr)
+		}
+	}
+
+	// -version is listed with the flags of every command
+	for _, cmd := range commands {
+		var stdout, stderr bytes.Buffer
+		runCommand([]string{"help", cmd.name}, &stdout, &stderr)
+		if !strings.Contains(stdout.String(), "  -version\n") {
+			t.Errorf("%s: help doesn't list -version:\n%s", cmd.name, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%f", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeCSV writes a header row followed by the rows of all tables. Several
+// tables are written as one CSV with a leading section column naming the
+// table. The header is the union of the columns of all tables and the
+// fields of missing columns are left empty.
+func writeCSV(w io.Writer, tables []Table) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	withSection := len(tables) > 1
+	var columns []string
+	for _, table := range tables {
+		for _, column := range table.Columns {
+			if !contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	header := columns
+	if withSection {
+		header = append([]string{"section"}, columns...)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			var record []string
+			if withSection {
+				record = append(record, table.Name)
+			}
+			for _, column := range columns {
+				if contains(table.Columns, column) {
+					record = append(record, formatValue(row.value(column)))
+				} else {
+					record = append(record, "")
+				}
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func contains(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func deltaRows(deltas keyfreq.Deltas) []Row {
+	var rows []Row
+	for _, delta := range deltas {
+		relative, _ := delta.Relative()
+		rows = append(rows, Row{
+			Key:      delta.Key,
+			Count:    delta.New,
+			Old:      delta.Old,
+			New:      delta.New,
+			Change:   delta.Change(),
+			Relative: relative,
+		})
+	}
+	return rows
+}
+
+// buildDiffTables returns the function and mode changes ordered by their
+// size, the top biggest movers among the functions used in both snapshots
+// and the functions that are new or disappeared
+func buildDiffTables(diff *keyfreq.Diff, top int) []Table {
+	changeColumns := []string{colKey, colOld, colNew, colChange, colRelative}
+	movers := diff.Funcs.Movers()
+	if top > 0 && len(movers) > top {
+		movers = movers[:top]
+	}
+	return []Table{
+		{Name: "functions", Title: "Function changes", Columns: changeColumns, Rows: deltaRows(diff.Funcs)},
+		{Name: "modes", Title: "Mode changes", Columns: changeColumns, Rows: deltaRows(diff.Modes)},
+		{Name: "movers", Title: "Biggest movers", Columns: changeColumns, Rows: deltaRows(movers)},
+		{Name: "new", Title: "New functions", Columns: []string{colKey, colNew}, Rows: deltaRows(diff.Funcs.Added())},
+		{Name: "disappeared", Title: "Disappeared functions", Columns: []string{colKey, colOld}, Rows: deltaRows(diff.Funcs.Removed())},
+	}
+}
+
+// runDiff implements "go-keyfreq diff [flags] old new"
+func runDiff(flags *flag.FlagSet, args []string, stdout, stderr io.Writer) int {
+	outFormat := flags.String("format", "csv", "output format of the report. Choose between csv, json, table, markdown and html")
+	top := flags.Int("top", 10, "number of biggest movers. 0 lists all")
+	recoverEntries := flags.Bool("recover", false, "skip malformed entries and report them instead of aborting")
+	var opts Opts
+	opts.addOutputFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+	if flags.NArg() != 2 {
+		return argsError(flags, stdout, stderr, fmt.Errorf("diff needs exactly two files: old new"))
+	}
+	format, err := OutFormatParse(*outFormat)
+	if err == nil && format == SVG {
+		err = fmt.Errorf("diff can't be written as svg")
+	}
+	if err != nil {
+		return argsError(flags, stdout, stderr, err)
+	}
+
+	opts.format = format
+	opts.recover = *recoverEntries
+	var snapshots []*keyfreq.Stats
+	for _, filename := range flags.Args() {
+		stats, err := parseFile(filename, opts, nil, stderr)
+		if err != nil {
+			return 1
+		}
+		snapshots = append(snapshots, stats)
+	}
+
+	tables := buildDiffTables(keyfreq.Compare(snapshots[0], snapshots[1]), *top)
+	err = writeOutput(opts.output, stdout, func(w io.Writer) error {
+		return writeTables(w, tables, opts)
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestRunDiff(t *testing.T) {
+	before := writeTempFile(t, `(((org-mode . next-line) . 10)
+ ((org-mode . previous-line) . 4)
+ ((text-mode . forward-word) . 6))`)
+	defer os.Remove(before)
+	after := writeTempFile(t, `(((org-mode . next-line) . 4)
+ ((org-mode . previous-line) . 5)
+ ((org-mode . avy-goto-char) . 8))`)
+	defer os.Remove(after)
+
+	testcases := map[string]struct {
+		args         []string
+		wantedCode   int
+		wantedStdout string
+	}{
+		"csv": {
+			args:       []string{"-top", "1", before, after},
+			wantedCode: 0,
+			wantedStdout: "section,key,old,new,change,relative\n" +
+				"functions,avy-goto-char,0,8,8,\n" +
+				"functions,forward-word,6,0,-6,-100.000000\n" +
+				"functions,next-line,10,4,-6,-60.000000\n" +
+				"functions,previous-line,4,5,1,25.000000\n" +
+				"modes,text-mode,6,0,-6,-100.000000\n" +
+				"modes,org-mode,14,17,3,21.428571\n" +
+				"movers,next-line,10,4,-6,-60.000000\n" +
+				"new,avy-goto-char,,8,,\n" +
+				"disappeared,forward-word,6,,,\n",
+		},
+		"table": {
+			args:       []string{"-format", "table", "-top", "1", before, after},
+			wantedCode: 0,
+			wantedStdout: "Function changes\n" +
+				"----------------\n" +
+				"key            old  new  change  relative\n" +
+				"avy-goto-char    0    8      +8\n" +
+				"forward-word     6    0      -6  -100.00%\n" +
+				"next-line       10    4      -6   -60.00%\n" +
+				"previous-line    4    5      +1    25.00%\n" +
+				"\n" +
+				"Mode changes\n" +
+				"------------\n" +
+				"key        old  new  change  relative\n" +
+				"text-mode    6    0      -6  -100.00%\n" +
+				"org-mode    14   17      +3    21.43%\n" +
+				"\n" +
+				"Biggest movers\n" +
+				"--------------\n" +
+				"key        old  new  change  relative\n" +
+				"next-line   10    4      -6   -60.00%\n" +
+				"\n" +
+				"New functions\n" +
+				"-------------\n" +
+				"key            new\n" +
+				"avy-goto-char    8\n" +
+				"\n" +
+				"Disappeared functions\n" +
+				"---------------------\n" +
+				"key           old\n" +
+				"forward-word    6\n",
+		},
+		"one file": {
+			args:       []string{before},
+			wantedCode: 2,
+		},
+		"svg": {
+			args:       []string{"-format", "svg", before, after},
+			wantedCode: 2,
+		},
+	}
+	for name, tc := range testcases {
+		var stdout, stderr bytes.Buffer
+		code := runCommand(append([]string{"diff"}, tc.args...), &stdout, &stderr)
+		if code != tc.wantedCode {
+			t.Errorf("%s: Got exit code %d but wanted %d: %s", name, code, tc.wantedCode, stderr.String())
+		}
+		if got := stdout.String(); got != tc.wantedStdout {
+			t.Errorf("%s: Got\n%s\nbut wanted\n%s", name, got, tc.wantedStdout)
+		}
+		if tc.wantedCode != 0 && stderr.Len() == 0 {
+			t.Errorf("%s: expected an error message", name)
+		}
+	}
+}
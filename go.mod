@@ -0,0 +1,3 @@
+module github.com/native-human/go-keyfreq
+
+go 1.16
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"html/template"
+	"io"
+)
+
+type htmlCell struct {
+	Text    string
+	Value   interface{}
+	Numeric bool
+}
+
+type htmlTable struct {
+	Title   string
+	Columns []string
+	Rows    [][]htmlCell
+}
+
+// the report has no external dependencies so that it can be opened from
+// disk. Clicking a column header sorts the table by that column.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>keyfreq report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.25em 0.75em; border-bottom: 1px solid #ddd; }
+th { cursor: pointer; background: #f4f4f4; text-align: left; }
+th:hover { background: #e8e8e8; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr:hover td { background: #fafafa; }
+</style>
+</head>
+<body>
+{{- range .}}
+<h2>{{.Title}}</h2>
+<table class="sortable">
+<thead>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}{{if .Numeric}}<td class="num" data-value="{{.Value}}">{{.Text}}</td>{{else}}<td>{{.Text}}</td>{{end}}{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, column) {
+    var ascending = false;
+    th.addEventListener("click", function () {
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      ascending = !ascending;
+      rows.sort(function (a, b) {
+        var x = a.cells[column], y = b.cells[column];
+        var cmp = x.dataset.value !== undefined
+          ? parseFloat(x.dataset.value) - parseFloat(y.dataset.value)
+          : x.textContent.localeCompare(y.textContent);
+        return ascending ? cmp : -cmp;
+      });
+      rows.forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// writeHTML renders the tables as a self-contained HTML page
+func writeHTML(w io.Writer, tables []Table) error {
+	var data []htmlTable
+	for _, table := range tables {
+		t := htmlTable{
+			Title:   table.Title,
+			Columns: table.Columns,
+		}
+		for _, row := range table.Rows {
+			cells := make([]htmlCell, len(table.Columns))
+			for j, column := range table.Columns {
+				v := row.value(column)
+				cells[j] = htmlCell{
+					Text:    formatCell(v),
+					Value:   v,
+					Numeric: isNumeric(column),
+				}
+			}
+			t.Rows = append(t.Rows, cells)
+		}
+		data = append(data, t)
+	}
+	return htmlTemplate.Execute(w, data)
+}
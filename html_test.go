@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func TestHTMLReport(t *testing.T) {
+	content := `(((org-mode . next-line) . 3000)
+ ((org-mode . org-cycle) . 1000))`
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var b bytes.Buffer
+	tables := buildTables([]input{{name: "<a>", stats: stats}}, Opts{mode: ALL, format: HTML, bySource: true})
+	if err := writeHTML(&b, tables); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := b.String()
+
+	wanted := []string{
+		"<h2>Funcs</h2>",
+		"<h2>Modes</h2>",
+		"<h2>Breakdown</h2>",
+		"<th>source</th><th>key</th><th>count</th><th>percent</th>",
+		`<tr><td>&lt;a&gt;</td><td>next-line</td><td class="num" data-value="3000">3,000</td><td class="num" data-value="75">75.00%</td></tr>`,
+		"<style>",
+		"<script>",
+	}
+	for _, w := range wanted {
+		if !strings.Contains(got, w) {
+			t.Errorf("HTML report does not contain '%s':\n%s", w, got)
+		}
+	}
+	for _, external := range []string{"<link", "src="} {
+		if strings.Contains(got, external) {
+			t.Errorf("HTML report is not self-contained, found '%s'", external)
+		}
+	}
+}
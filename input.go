@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+	"github.com/native-human/go-keyfreq/sexp"
+)
+
+// stringList is a flag.Value collecting every occurrence of a flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// input is a parsed keyfreq file
+type input struct {
+	name  string
+	stats *keyfreq.Stats
+}
+
+// expandInputs resolves glob patterns and directories to the list of files
+// to read. Directories contribute all regular files directly inside them.
+func expandInputs(patterns []string) ([]string, error) {
+	var filenames []string
+	for _, pattern := range patterns {
+		if pattern == stdinName {
+			filenames = append(filenames, pattern)
+			continue
+		}
+		if strings.ContainsAny(pattern, "*?[") {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %s", pattern, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match '%s'", pattern)
+			}
+			filenames = append(filenames, matches...)
+			continue
+		}
+
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			filenames = append(filenames, pattern)
+			continue
+		}
+		entries, err := ioutil.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() {
+				filenames = append(filenames, filepath.Join(pattern, entry.Name()))
+			}
+		}
+	}
+	return filenames, nil
+}
+
+// stdinName is the input filename reading standard input
+const stdinName = "-"
+
+// stdin is read for the input filename "-"
+var stdin io.Reader = os.Stdin
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress detects gzip and bzip2 streams by their magic bytes and
+// returns a reader of the uncompressed content. Other input is returned
+// unchanged.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return nil, fmt.Errorf("zstd compressed input is not supported")
+	}
+	return br, nil
+}
+
+// openInput opens filename, or standard input for "-", and decompresses it
+// if necessary. The returned close function must be called when done.
+func openInput(filename string) (io.Reader, func() error, error) {
+	var r io.Reader = stdin
+	closeFn := func() error { return nil }
+	if filename != stdinName {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = file
+		closeFn = file.Close
+	}
+	dr, err := decompress(r)
+	if err != nil {
+		closeFn()
+		return nil, nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return dr, closeFn, nil
+}
+
+// readInput reads the whole, decompressed content of filename. Parse errors
+// quote lines of it.
+func readInput(filename string) ([]byte, error) {
+	r, closeFn, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return src, nil
+}
+
+// parseFile reads filename and reports skipped entries and parse errors on
+// stderr. Only entries selected by filter are counted unless it is nil. The
+// returned stats are nil if the file can't be opened.
+func parseFile(filename string, opts Opts, filter *keyfreq.Filter, stderr io.Writer) (*keyfreq.Stats, error) {
+	src, err := readInput(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return nil, err
+	}
+
+	parser := keyfreq.NewParser(bytes.NewReader(src))
+	parser.Recover = opts.recover
+	if filter != nil {
+		parser.Filter = filter.Match
+	}
+	stats, err := parser.Parse()
+	printParseErrors(stderr, filename, src, parser.Diagnostics(), err)
+	return stats, err
+}
+
+// printParseErrors reports the skipped entries and the parse error of
+// filename on stderr as file:line:col: message followed by an excerpt of
+// src
+func printParseErrors(stderr io.Writer, filename string, src []byte, diagnostics []sexp.PosError, err error) {
+	for _, d := range diagnostics {
+		printParseError(stderr, filename, src, d, " (entry skipped)")
+	}
+	if err != nil {
+		printParseError(stderr, filename, src, err, "")
+	}
+}
+
+// printParseError reports one error. Errors without a position only get the
+// filename.
+func printParseError(stderr io.Writer, filename string, src []byte, err error, note string) {
+	posErr, ok := err.(sexp.LexPosError)
+	if !ok {
+		fmt.Fprintf(stderr, "%s: %s%s\n", filename, err, note)
+		return
+	}
+	fmt.Fprintf(stderr, "%s%s\n%s", posErr.InFile(filename), note, posErr.Excerpt(src))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// writeJSON writes one object with an array of rows per table:
+//
+//	{"functions": [{"key": "next-line", "count": 3, "percent": 75}, ...]}
+func writeJSON(w io.Writer, tables []Table) error {
+	report := make(map[string][]map[string]interface{})
+	for _, table := range tables {
+		rows := []map[string]interface{}{}
+		for _, row := range table.Rows {
+			object := make(map[string]interface{})
+			for _, column := range table.Columns {
+				object[column] = row.value(column)
+			}
+			rows = append(rows, object)
+		}
+		report[table.Name] = rows
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,115 @@
+package keyfreq
+
+import (
+	"sort"
+)
+
+// Delta is the change of a function or mode count between two snapshots
+type Delta struct {
+	Key string
+	Old uint64
+	New uint64
+}
+
+// Change returns the absolute change from Old to New
+func (d Delta) Change() int64 {
+	return int64(d.New) - int64(d.Old)
+}
+
+// Relative returns the change in percent of Old. ok is false for keys that
+// are new in the second snapshot.
+func (d Delta) Relative() (relative float64, ok bool) {
+	if d.Old == 0 {
+		return 0, false
+	}
+	return 100.0 * float64(d.Change()) / float64(d.Old), true
+}
+
+// Deltas are ordered by the size of the change, biggest first
+type Deltas []Delta
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (d Deltas) Len() int {
+	return len(d)
+}
+
+func (d Deltas) Less(i, j int) bool {
+	ci, cj := abs(d[i].Change()), abs(d[j].Change())
+	if ci != cj {
+		return ci > cj
+	}
+	return d[i].Key < d[j].Key
+}
+
+func (d Deltas) Swap(i, j int) {
+	d[i], d[j] = d[j], d[i]
+}
+
+// Added returns the deltas of keys missing in the old snapshot
+func (d Deltas) Added() Deltas {
+	var added Deltas
+	for _, delta := range d {
+		if delta.Old == 0 {
+			added = append(added, delta)
+		}
+	}
+	return added
+}
+
+// Removed returns the deltas of keys missing in the new snapshot
+func (d Deltas) Removed() Deltas {
+	var removed Deltas
+	for _, delta := range d {
+		if delta.New == 0 {
+			removed = append(removed, delta)
+		}
+	}
+	return removed
+}
+
+// Movers returns the deltas of keys present in both snapshots whose count
+// changed
+func (d Deltas) Movers() Deltas {
+	var movers Deltas
+	for _, delta := range d {
+		if delta.Old != 0 && delta.New != 0 && delta.Change() != 0 {
+			movers = append(movers, delta)
+		}
+	}
+	return movers
+}
+
+// Diff compares two snapshots of keyfreq data
+type Diff struct {
+	Funcs Deltas
+	Modes Deltas
+}
+
+func compareCounts(before, after map[string]uint64) Deltas {
+	var deltas Deltas
+	for k, c := range before {
+		deltas = append(deltas, Delta{Key: k, Old: c, New: after[k]})
+	}
+	for k, c := range after {
+		if _, ok := before[k]; !ok {
+			deltas = append(deltas, Delta{Key: k, New: c})
+		}
+	}
+	sort.Sort(deltas)
+	return deltas
+}
+
+// Compare returns the per function and per mode changes from before to
+// after
+func Compare(before, after *Stats) *Diff {
+	return &Diff{
+		Funcs: compareCounts(before.totalFunc, after.totalFunc),
+		Modes: compareCounts(before.totalMode, after.totalMode),
+	}
+}
@@ -0,0 +1,108 @@
+package keyfreq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	before, err := Parse(strings.NewReader(`(((org-mode . next-line) . 10)
+ ((org-mode . previous-line) . 4)
+ ((text-mode . forward-word) . 6))`))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	after, err := Parse(strings.NewReader(`(((org-mode . next-line) . 4)
+ ((org-mode . previous-line) . 5)
+ ((org-mode . avy-goto-char) . 8))`))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	diff := Compare(before, after)
+
+	wantedFuncs := Deltas{
+		{Key: "avy-goto-char", Old: 0, New: 8},
+		{Key: "forward-word", Old: 6, New: 0},
+		{Key: "next-line", Old: 10, New: 4},
+		{Key: "previous-line", Old: 4, New: 5},
+	}
+	if !reflect.DeepEqual(diff.Funcs, wantedFuncs) {
+		t.Errorf("Got functions '%v' but wanted '%v'", diff.Funcs, wantedFuncs)
+	}
+	wantedModes := Deltas{
+		{Key: "text-mode", Old: 6, New: 0},
+		{Key: "org-mode", Old: 14, New: 17},
+	}
+	if !reflect.DeepEqual(diff.Modes, wantedModes) {
+		t.Errorf("Got modes '%v' but wanted '%v'", diff.Modes, wantedModes)
+	}
+
+	if got := diff.Funcs.Added(); !reflect.DeepEqual(got, wantedFuncs[:1]) {
+		t.Errorf("Got added '%v' but wanted '%v'", got, wantedFuncs[:1])
+	}
+	if got := diff.Funcs.Removed(); !reflect.DeepEqual(got, wantedFuncs[1:2]) {
+		t.Errorf("Got removed '%v' but wanted '%v'", got, wantedFuncs[1:2])
+	}
+	if got := diff.Funcs.Movers(); !reflect.DeepEqual(got, wantedFuncs[2:]) {
+		t.Errorf("Got movers '%v' but wanted '%v'", got, wantedFuncs[2:])
+	}
+}
+
+func TestMoversUnchanged(t *testing.T) {
+	input := `(((org-mode . next-line) . 3)
+ ((org-mode . org-cycle) . 5))`
+	before, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	after, err := Parse(strings.NewReader(`(((org-mode . next-line) . 3)
+ ((org-mode . org-cycle) . 7))`))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+
+	wanted := Deltas{{Key: "org-cycle", Old: 5, New: 7}}
+	if got := Compare(before, after).Funcs.Movers(); !reflect.DeepEqual(got, wanted) {
+		t.Errorf("Got movers '%v' but wanted '%v'", got, wanted)
+	}
+	if got := Compare(before, before).Funcs.Movers(); len(got) != 0 {
+		t.Errorf("Got movers '%v' comparing a snapshot with itself", got)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	testcases := map[string]struct {
+		delta          Delta
+		wantedChange   int64
+		wantedRelative float64
+		wantedOk       bool
+	}{
+		"increase": {
+			delta:          Delta{Old: 4, New: 5},
+			wantedChange:   1,
+			wantedRelative: 25,
+			wantedOk:       true,
+		},
+		"decrease": {
+			delta:          Delta{Old: 10, New: 4},
+			wantedChange:   -6,
+			wantedRelative: -60,
+			wantedOk:       true,
+		},
+		"added": {
+			delta:        Delta{Old: 0, New: 8},
+			wantedChange: 8,
+			wantedOk:     false,
+		},
+	}
+	for name, tc := range testcases {
+		if got := tc.delta.Change(); got != tc.wantedChange {
+			t.Errorf("%s: Got change %d but wanted %d", name, got, tc.wantedChange)
+		}
+		relative, ok := tc.delta.Relative()
+		if ok != tc.wantedOk || relative != tc.wantedRelative {
+			t.Errorf("%s: Got relative %f (%t) but wanted %f (%t)", name, relative, ok, tc.wantedRelative, tc.wantedOk)
+		}
+	}
+}
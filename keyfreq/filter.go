@@ -0,0 +1,100 @@
+package keyfreq
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern matches mode or function names
+type Pattern interface {
+	MatchString(s string) bool
+}
+
+// CompilePattern compiles a glob like "org-*" or, when enclosed in slashes
+// like "/^org-(agenda|capture)/", a regular expression. Globs must match the
+// whole name and support *, ? and [...] character classes.
+func CompilePattern(pattern string) (Pattern, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression '%s': %s", pattern, err)
+		}
+		return re, nil
+	}
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob '%s': %s", pattern, err)
+	}
+	return re, nil
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	inClass := false
+	// classStart is set right after the opening bracket of a class, where
+	// the glob negation ! becomes ^
+	classStart := false
+	for _, r := range glob {
+		switch {
+		case classStart && r == '!':
+			classStart = false
+			b.WriteRune('^')
+		case inClass:
+			classStart = false
+			if r == ']' {
+				inClass = false
+			}
+			if r == '\\' {
+				b.WriteString(`\\`)
+			} else {
+				b.WriteRune(r)
+			}
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		case r == '[':
+			inClass = true
+			classStart = true
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// Filter selects entries by their mode and function. An entry is selected
+// if it matches any of the include patterns, or there are none, and none of
+// the exclude patterns.
+type Filter struct {
+	IncludeModes []Pattern
+	ExcludeModes []Pattern
+	IncludeFuncs []Pattern
+	ExcludeFuncs []Pattern
+}
+
+func matchesAny(patterns []Pattern, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func selects(include, exclude []Pattern, s string) bool {
+	if len(include) > 0 && !matchesAny(include, s) {
+		return false
+	}
+	return !matchesAny(exclude, s)
+}
+
+// Match reports whether mf is selected. It can be used as Parser.Filter.
+func (f *Filter) Match(mf ModeFunc) bool {
+	return selects(f.IncludeModes, f.ExcludeModes, mf.Mode) &&
+		selects(f.IncludeFuncs, f.ExcludeFuncs, mf.Function)
+}
@@ -0,0 +1,134 @@
+package keyfreq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	testcases := map[string]struct {
+		pattern   string
+		matches   []string
+		unmatched []string
+	}{
+		"literal": {
+			pattern:   "self-insert-command",
+			matches:   []string{"self-insert-command"},
+			unmatched: []string{"self-insert-command-x", "xself-insert-command"},
+		},
+		"star": {
+			pattern:   "org-*",
+			matches:   []string{"org-mode", "org-"},
+			unmatched: []string{"orgmode", "my-org-mode"},
+		},
+		"question mark": {
+			pattern:   "c?-mode",
+			matches:   []string{"cc-mode"},
+			unmatched: []string{"c-mode", "ccc-mode"},
+		},
+		"class": {
+			pattern:   "[ct]-mode",
+			matches:   []string{"c-mode", "t-mode"},
+			unmatched: []string{"x-mode"},
+		},
+		"negated class": {
+			pattern:   "[!o]*",
+			matches:   []string{"text-mode", "!-mode"},
+			unmatched: []string{"org-mode"},
+		},
+		"meta characters": {
+			pattern:   "c++-mode",
+			matches:   []string{"c++-mode"},
+			unmatched: []string{"cc-mode"},
+		},
+		"regexp": {
+			pattern:   "/^org-(agenda|capture)/",
+			matches:   []string{"org-agenda", "org-capture-finalize"},
+			unmatched: []string{"org-mode"},
+		},
+	}
+	for name, tc := range testcases {
+		p, err := CompilePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		for _, s := range tc.matches {
+			if !p.MatchString(s) {
+				t.Errorf("%s: '%s' does not match '%s'", name, tc.pattern, s)
+			}
+		}
+		for _, s := range tc.unmatched {
+			if p.MatchString(s) {
+				t.Errorf("%s: '%s' unexpectedly matches '%s'", name, tc.pattern, s)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"/(/", "[a-"} {
+		if _, err := CompilePattern(invalid); err == nil {
+			t.Errorf("CompilePattern accepted invalid pattern '%s'", invalid)
+		}
+	}
+}
+
+func mustCompile(t *testing.T, patterns ...string) []Pattern {
+	var compiled []Pattern
+	for _, pattern := range patterns {
+		p, err := CompilePattern(pattern)
+		if err != nil {
+			t.Fatalf("can't compile '%s': %s", pattern, err)
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled
+}
+
+func TestParseFilter(t *testing.T) {
+	input := `(((org-mode . next-line) . 3)
+ ((org-mode . self-insert-command) . 20)
+ ((minibuffer-inactive-mode . next-line) . 4)
+ ((text-mode . next-line) . 5))`
+	testcases := map[string]struct {
+		filter      Filter
+		wantedTotal uint64
+	}{
+		"none": {
+			filter:      Filter{},
+			wantedTotal: 32,
+		},
+		"exclude": {
+			filter: Filter{
+				ExcludeModes: mustCompile(t, "minibuffer-*"),
+				ExcludeFuncs: mustCompile(t, "self-insert-command"),
+			},
+			wantedTotal: 8,
+		},
+		"include": {
+			filter: Filter{
+				IncludeModes: mustCompile(t, "org-mode", "/^text/"),
+			},
+			wantedTotal: 28,
+		},
+		"include and exclude": {
+			filter: Filter{
+				IncludeModes: mustCompile(t, "*-mode"),
+				ExcludeModes: mustCompile(t, "text-mode"),
+				IncludeFuncs: mustCompile(t, "next-*"),
+			},
+			wantedTotal: 7,
+		},
+	}
+	for name, tc := range testcases {
+		parser := NewParser(strings.NewReader(input))
+		parser.Filter = tc.filter.Match
+		stats, err := parser.Parse()
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		if got := stats.Total(); got != tc.wantedTotal {
+			t.Errorf("%s: got total %d but wanted %d", name, got, tc.wantedTotal)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package keyfreq
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/native-human/go-keyfreq/sexp"
+)
+
+// synthetic function names of anonymous commands
+const (
+	// LambdaName stands for lambda expressions, closures and byte-code
+	// functions
+	LambdaName = "<lambda>"
+	// KbdMacroName stands for keyboard macros given as string or vector
+	KbdMacroName = "<kbd-macro>"
+)
+
+// Parser reads the alist saved by keyfreq.el:
+//
+//	(((mode . function) . count) ...)
+type Parser struct {
+	// Recover makes the parser skip malformed entries instead of aborting.
+	// The skipped errors are available from Diagnostics.
+	Recover bool
+
+	// Filter selects the entries that are counted. All entries are counted
+	// if it is nil.
+	Filter func(ModeFunc) bool
+
+	// KeepSource makes the parser record the source text of anonymous
+	// commands, which are counted under a synthetic name. The texts are
+	// available from Stats.Sources.
+	KeepSource bool
+
+	reader      *sexp.Reader
+	stats       *Stats
+	diagnostics []sexp.PosError
+}
+
+type ModeFunc struct {
+	Function string
+	Mode     string
+}
+
+// token consumes the next lexeme of the reader. The parser only calls token
+// while it expects more input, so the end of the input is an error.
+func (p *Parser) token() (sexp.Lexeme, sexp.PosError) {
+	item, err := p.reader.Token()
+	if err != nil {
+		return item, p.posError(err)
+	}
+	return item, nil
+}
+
+// posError returns the PosError of an error of the reader, which returns
+// io.EOF for the end of the input outside of any list
+func (p *Parser) posError(err error) sexp.PosError {
+	if posErr, ok := err.(sexp.PosError); ok {
+		return posErr
+	}
+	return sexp.Unexpected(p.reader.Position(), "", "end of file")
+}
+
+// quote returns s as it appears in error messages
+func quote(s string) string {
+	return "'" + s + "'"
+}
+
+// readRoot reads the root list, which may be quoted as '(...) or
+// (quote (...)). The entries are read one by one so that the counts of the
+// entries before a syntax error are kept.
+func (p *Parser) readRoot() sexp.PosError {
+	item, err := p.token()
+	if err != nil {
+		return err
+	}
+	if item.Token() == sexp.QUOTE {
+		if item, err = p.token(); err != nil {
+			return err
+		}
+	}
+	if item.Token() != sexp.OPAREN {
+		return sexp.Unexpected(item.Start(), "symbol '('", quote(item.Content()))
+	}
+	next, _ := p.reader.Peek()
+	wrapped := next.Token() == sexp.IDENT && next.Content() == "quote"
+	if wrapped {
+		p.token()
+		if item, err = p.token(); err != nil {
+			return err
+		}
+		if item.Token() != sexp.OPAREN {
+			return sexp.Unexpected(item.Start(), "symbol '(' after quote", quote(item.Content()))
+		}
+	}
+
+	depth := p.reader.Depth()
+	for p.reader.More() {
+		entry, readErr := p.reader.Read()
+		if readErr != nil {
+			if !p.Recover || !p.skipTo(depth) {
+				return p.posError(readErr)
+			}
+			p.diagnostics = append(p.diagnostics, p.posError(readErr))
+			continue
+		}
+		if err := p.readCount(entry); err != nil {
+			if !p.Recover {
+				return err
+			}
+			p.diagnostics = append(p.diagnostics, err)
+		}
+	}
+
+	// the reader makes sure that the root list is closed by ')'
+	if _, err := p.token(); err != nil {
+		return err
+	}
+	if wrapped {
+		if item, err = p.token(); err != nil {
+			return err
+		}
+		if item.Token() != sexp.CPAREN {
+			return sexp.Unexpected(item.Start(), "symbol ')' after quoted list", quote(item.Content()))
+		}
+	}
+	return nil
+}
+
+// skipTo consumes the rest of a malformed entry up to the list depth of
+// the root list. It reports false if that is not possible because of an
+// error of the lexer, unbalanced delimiters or the end of the input.
+func (p *Parser) skipTo(depth int) bool {
+	for p.reader.Depth() > depth {
+		if _, err := p.reader.Token(); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// cons returns the car and cdr of the cons cell (car . cdr)
+func cons(v sexp.Value) (sexp.Value, sexp.Value, sexp.PosError) {
+	l, ok := v.(sexp.List)
+	switch {
+	case !ok || len(l.Items) == 0:
+		return nil, nil, sexp.Unexpected(v.Start(), "symbol '('", quote(v.String()))
+	case len(l.Items) > 1:
+		return nil, nil, sexp.Unexpected(l.Items[1].Start(), "symbol '.'", quote(l.Items[1].String()))
+	case l.Tail == nil:
+		return nil, nil, sexp.Unexpected(l.Close(), "symbol '.'", quote(")"))
+	}
+	return l.Items[0], l.Tail, nil
+}
+
+// readAnonymous returns the synthetic name of an anonymous command and its
+// source text
+func readAnonymous(command sexp.Value) (string, string, sexp.PosError) {
+	switch command := command.(type) {
+	case sexp.String, sexp.Vector:
+		return KbdMacroName, command.String(), nil
+	case sexp.ByteCode:
+		return LambdaName, command.String(), nil
+	case sexp.List:
+		if len(command.Items) == 0 {
+			break
+		}
+		head, ok := command.Items[0].(sexp.Symbol)
+		if !ok || (head.Name != "lambda" && head.Name != "closure") {
+			return "", "", sexp.Unexpected(command.Items[0].Start(), "lambda", quote(command.Items[0].String()))
+		}
+		return LambdaName, command.String(), nil
+	}
+	return "", "", sexp.Unexpected(command.Start(), "command", quote(command.String()))
+}
+
+// readModeFunction reads (mode . function). The source text is only set for
+// anonymous commands.
+func readModeFunction(v sexp.Value) (ModeFunc, string, sexp.PosError) {
+	var mf ModeFunc
+	var source string
+	mode, function, err := cons(v)
+	if err != nil {
+		return mf, source, err
+	}
+
+	modeSymbol, ok := mode.(sexp.Symbol)
+	if !ok {
+		return mf, source, sexp.Unexpected(mode.Start(), "mode symbol", quote(mode.String()))
+	}
+	mf.Mode = modeSymbol.Name
+
+	if symbol, ok := function.(sexp.Symbol); ok {
+		mf.Function = symbol.Name
+	} else if mf.Function, source, err = readAnonymous(function); err != nil {
+		return mf, source, err
+	}
+	return mf, source, nil
+}
+
+// readCount reads one ((mode . function) . count) entry
+func (p *Parser) readCount(entry sexp.Value) sexp.PosError {
+	key, count, err := cons(entry)
+	if err != nil {
+		return err
+	}
+	mf, source, err := readModeFunction(key)
+	if err != nil {
+		return err
+	}
+
+	number, ok := count.(sexp.Number)
+	if !ok {
+		return sexp.Unexpected(count.Start(), "number", quote(count.String()))
+	}
+	u, converr := strconv.ParseUint(number.Text, 10, 64)
+	if converr != nil {
+		return sexp.PosErrorf(count.Start(), "can't convert count '%s' to unsigned integer: %s", number.Text, converr)
+	}
+
+	if p.Filter == nil || p.Filter(mf) {
+		p.stats.add(mf, u)
+		if source != "" {
+			p.stats.addForm(mf, source, u)
+			if p.KeepSource {
+				p.stats.addSource(mf, source)
+			}
+		}
+	}
+	return nil
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		reader: sexp.NewReader(r),
+		stats:  NewStats(),
+	}
+}
+
+// Parse reads the whole root list and returns the accumulated counts. On
+// error the counts of all entries read so far are returned together with
+// the PosError.
+func (p *Parser) Parse() (*Stats, error) {
+	if err := p.readRoot(); err != nil {
+		return p.stats, err
+	}
+	return p.stats, nil
+}
+
+// Diagnostics returns the errors of the entries skipped in Recover mode
+func (p *Parser) Diagnostics() []sexp.PosError {
+	return p.diagnostics
+}
+
+// Parse reads a keyfreq file from r
+func Parse(r io.Reader) (*Stats, error) {
+	return NewParser(r).Parse()
+}
@@ -0,0 +1,499 @@
+package keyfreq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/sexp"
+)
+
+func TestParserReadFunc(t *testing.T) {
+	testcases := map[string]struct {
+		input        string
+		wanted       ModeFunc
+		wantedSource string
+	}{
+		"basic": {
+			input: "(my-mode . my-function)",
+			wanted: ModeFunc{
+				Function: "my-function",
+				Mode:     "my-mode",
+			},
+		},
+		"lambda": {
+			input:        "(my-mode . (lambda  ()\n  (interactive) (forward-line   2)))",
+			wanted:       ModeFunc{Function: LambdaName, Mode: "my-mode"},
+			wantedSource: "(lambda () (interactive) (forward-line 2))",
+		},
+		"closure": {
+			input:        "(my-mode . (closure (t) nil (interactive) (foo\\ bar)))",
+			wanted:       ModeFunc{Function: LambdaName, Mode: "my-mode"},
+			wantedSource: "(closure (t) nil (interactive) (foo\\ bar))",
+		},
+		"byte-code": {
+			input:        "(my-mode . #[0 \"\\300\\301!\\207\" [2 forward-line] 2 nil nil])",
+			wanted:       ModeFunc{Function: LambdaName, Mode: "my-mode"},
+			wantedSource: "#[0 \"\\300\\301!\\207\" [2 forward-line] 2 nil nil]",
+		},
+		"string macro": {
+			input:        "(my-mode . \"\\C-a\\C-k\")",
+			wanted:       ModeFunc{Function: KbdMacroName, Mode: "my-mode"},
+			wantedSource: "\"\\C-a\\C-k\"",
+		},
+		"vector macro": {
+			input:        "(my-mode . [1 11 return])",
+			wanted:       ModeFunc{Function: KbdMacroName, Mode: "my-mode"},
+			wantedSource: "[1 11 return]",
+		},
+	}
+	for name, tc := range testcases {
+		v, readErr := sexp.NewReader(strings.NewReader(tc.input)).Read()
+		if readErr != nil {
+			t.Errorf("%s: unexpected read error: '%s'", name, readErr)
+			continue
+		}
+
+		got, source, err := readModeFunction(v)
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		if got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+		if source != tc.wantedSource {
+			t.Errorf("%s: Got source '%s' but wanted '%s'", name, source, tc.wantedSource)
+		}
+	}
+}
+
+func TestKeepSource(t *testing.T) {
+	input := `(((org-mode . (lambda () (interactive) (foo))) . 2)
+ ((org-mode . (lambda () (interactive)  (foo))) . 1)
+ ((org-mode . (lambda () (interactive) (bar))) . 4)
+ ((org-mode . next-line) . 3))`
+	lambda := ModeFunc{Mode: "org-mode", Function: LambdaName}
+
+	stats, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	if got := stats.PairCount(lambda); got != 7 {
+		t.Errorf("Got %d lambda calls but wanted 7", got)
+	}
+	if got := stats.Sources(lambda); len(got) != 0 {
+		t.Errorf("Got sources %v without KeepSource", got)
+	}
+
+	parser := NewParser(strings.NewReader(input))
+	parser.KeepSource = true
+	stats, err = parser.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	wanted := []string{"(lambda () (interactive) (bar))", "(lambda () (interactive) (foo))"}
+	got := stats.Sources(lambda)
+	if strings.Join(got, "\n") != strings.Join(wanted, "\n") {
+		t.Errorf("Got sources %v but wanted %v", got, wanted)
+	}
+	merged := NewStats()
+	merged.Merge(stats)
+	if got := merged.Sources(lambda); len(got) != len(wanted) {
+		t.Errorf("Got merged sources %v but wanted %v", got, wanted)
+	}
+}
+
+func TestParse(t *testing.T) {
+	testcases := map[string]struct {
+		input       string
+		wantedFuncs map[string]uint64
+		wantedModes map[string]uint64
+		wantedPairs map[ModeFunc]uint64
+		wantedTotal uint64
+	}{
+		"basic": {
+			input: "(((fundamental-mode . ido-find-file) . 8))",
+			wantedFuncs: map[string]uint64{
+				"ido-find-file": 8,
+			},
+			wantedModes: map[string]uint64{
+				"fundamental-mode": 8,
+			},
+			wantedPairs: map[ModeFunc]uint64{
+				{Mode: "fundamental-mode", Function: "ido-find-file"}: 8,
+			},
+			wantedTotal: 8,
+		},
+		"accumulate": {
+			input: `(((org-mode . next-line) . 3)
+ ((org-mode . previous-line) . 2)
+ ((text-mode . next-line) . 5))`,
+			wantedFuncs: map[string]uint64{
+				"next-line":     8,
+				"previous-line": 2,
+			},
+			wantedModes: map[string]uint64{
+				"org-mode":  5,
+				"text-mode": 5,
+			},
+			wantedPairs: map[ModeFunc]uint64{
+				{Mode: "org-mode", Function: "next-line"}:      3,
+				{Mode: "org-mode", Function: "previous-line"}:  2,
+				{Mode: "text-mode", Function: "next-line"}:     5,
+				{Mode: "text-mode", Function: "previous-line"}: 0,
+			},
+			wantedTotal: 10,
+		},
+		"quoted": {
+			input: ";; -*- coding: utf-8 -*-\n'(((org-mode . next-line) . 3) ; comment\n ((org-mode . org-cycle) . 2))\n",
+			wantedFuncs: map[string]uint64{
+				"next-line": 3,
+				"org-cycle": 2,
+			},
+			wantedModes: map[string]uint64{
+				"org-mode": 5,
+			},
+			wantedTotal: 5,
+		},
+		"quote form": {
+			input: "(quote (((org-mode . next-line) . 3)))",
+			wantedFuncs: map[string]uint64{
+				"next-line": 3,
+			},
+			wantedModes: map[string]uint64{
+				"org-mode": 3,
+			},
+			wantedTotal: 3,
+		},
+		"empty": {
+			input:       "'()",
+			wantedTotal: 0,
+		},
+	}
+	for name, tc := range testcases {
+		stats, err := Parse(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		for f, c := range tc.wantedFuncs {
+			if got := stats.FuncCount(f); got != c {
+				t.Errorf("%s: function '%s' got count %d but wanted %d", name, f, got, c)
+			}
+		}
+		for m, c := range tc.wantedModes {
+			if got := stats.ModeCount(m); got != c {
+				t.Errorf("%s: mode '%s' got count %d but wanted %d", name, m, got, c)
+			}
+		}
+		for mf, c := range tc.wantedPairs {
+			if got := stats.PairCount(mf); got != c {
+				t.Errorf("%s: pair '%s' got count %d but wanted %d", name, mf, got, c)
+			}
+		}
+		if len(stats.Funcs()) != len(tc.wantedFuncs) {
+			t.Errorf("%s: got %d functions but wanted %d", name, len(stats.Funcs()), len(tc.wantedFuncs))
+		}
+		if len(stats.Modes()) != len(tc.wantedModes) {
+			t.Errorf("%s: got %d modes but wanted %d", name, len(stats.Modes()), len(tc.wantedModes))
+		}
+		if got := stats.Total(); got != tc.wantedTotal {
+			t.Errorf("%s: got total %d but wanted %d", name, got, tc.wantedTotal)
+		}
+	}
+}
+
+func TestModeFuncs(t *testing.T) {
+	input := `(((org-mode . next-line) . 3)
+ ((org-mode . previous-line) . 2)
+ ((org-mode . org-cycle) . 7)
+ ((text-mode . next-line) . 5))`
+	testcases := map[string]struct {
+		mode   string
+		wanted Countees
+	}{
+		"org-mode": {
+			mode: "org-mode",
+			wanted: Countees{
+				{Key: "org-cycle", Count: 7},
+				{Key: "next-line", Count: 3},
+				{Key: "previous-line", Count: 2},
+			},
+		},
+		"text-mode": {
+			mode: "text-mode",
+			wanted: Countees{
+				{Key: "next-line", Count: 5},
+			},
+		},
+		"unknown": {
+			mode:   "c-mode",
+			wanted: nil,
+		},
+	}
+	stats, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	for name, tc := range testcases {
+		got := stats.ModeFuncs(tc.mode)
+		if len(got) != len(tc.wanted) {
+			t.Errorf("%s: got %d functions but wanted %d", name, len(got), len(tc.wanted))
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.wanted[i] {
+				t.Errorf("%s: function %d: Got '%v' but wanted '%v'", name, i, got[i], tc.wanted[i])
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testcases := map[string]struct {
+		input       string
+		wantedRow   uint
+		wantedCol   uint
+		wantedTotal uint64
+	}{
+		"truncated": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . ",
+			wantedRow:   1,
+			wantedCol:   14,
+			wantedTotal: 1,
+		},
+		"missing count": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . next-line) . org-mode))",
+			wantedRow:   1,
+			wantedCol:   27,
+			wantedTotal: 1,
+		},
+		"unexpected character": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . ,next-line) . 2))",
+			wantedRow:   1,
+			wantedCol:   14,
+			wantedTotal: 1,
+		},
+		"list command": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . (keymap foo)) . 2))",
+			wantedRow:   1,
+			wantedCol:   15,
+			wantedTotal: 1,
+		},
+		"unbalanced": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . [1 2)) . 2))",
+			wantedRow:   1,
+			wantedCol:   18,
+			wantedTotal: 1,
+		},
+		"unclosed quote form": {
+			input:       "(quote (((org-mode . next-line) . 1)) foo)",
+			wantedRow:   0,
+			wantedCol:   38,
+			wantedTotal: 1,
+		},
+		"no root list": {
+			input:       "org-mode",
+			wantedRow:   0,
+			wantedCol:   0,
+			wantedTotal: 0,
+		},
+	}
+	for name, tc := range testcases {
+		stats, err := Parse(strings.NewReader(tc.input))
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		posErr, ok := err.(sexp.PosError)
+		if !ok {
+			t.Errorf("%s: expected a PosError but got '%s'", name, err)
+			continue
+		}
+		if posErr.GetRow() != tc.wantedRow || posErr.GetCol() != tc.wantedCol {
+			t.Errorf("%s: Got error at %d:%d but wanted %d:%d", name,
+				posErr.GetRow(), posErr.GetCol(), tc.wantedRow, tc.wantedCol)
+		}
+		if got := stats.Total(); got != tc.wantedTotal {
+			t.Errorf("%s: got total %d but wanted %d", name, got, tc.wantedTotal)
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	_, err := Parse(strings.NewReader("(((org-mode . next-line) . 1)\n ((org-mode . next-line) . org-mode))"))
+	posErr, ok := err.(sexp.LexPosError)
+	if !ok {
+		t.Fatalf("expected a LexPosError but got '%v'", err)
+	}
+	if posErr.Expected != "number" || posErr.Found != "'org-mode'" {
+		t.Errorf("Got expected %q and found %q", posErr.Expected, posErr.Found)
+	}
+	if got := posErr.InFile("a.keyfreq").Error(); got != "a.keyfreq:2:28: expected number but got 'org-mode'" {
+		t.Errorf("Got error '%s'", got)
+	}
+}
+
+// position is the comparable part of a sexp.Position
+type position struct {
+	row uint
+	col uint
+	pos uint
+}
+
+func TestParseRecover(t *testing.T) {
+	testcases := map[string]struct {
+		input             string
+		wantedErr         bool
+		wantedDiagnostics []position
+		wantedTotal       uint64
+	}{
+		"valid": {
+			input:       "(((org-mode . next-line) . 1))",
+			wantedTotal: 1,
+		},
+		"bad count": {
+			input: `(((org-mode . next-line) . 1)
+ ((org-mode . next-line) . org-mode)
+ ((org-mode . next-line) . 2))`,
+			wantedDiagnostics: []position{{row: 1, col: 27, pos: 57}},
+			wantedTotal:       3,
+		},
+		"nested garbage": {
+			input: `(((org-mode . next-line) . 1)
+ ((org-mode (a (b)) . next-line) . 4)
+ ((org-mode . next-line) . 2))`,
+			wantedDiagnostics: []position{{row: 1, col: 12, pos: 42}},
+			wantedTotal:       3,
+		},
+		"closed early": {
+			input: `(((org-mode . next-line) . 1)
+ ((org-mode . next-line))
+ ((org-mode . next-line) . 2))`,
+			wantedDiagnostics: []position{{row: 1, col: 24, pos: 54}},
+			wantedTotal:       3,
+		},
+		"several": {
+			input: `(((org-mode . next-line) . 1)
+ ((org-mode next-line) . 8)
+ ((org-mode . next-line) . 2)
+ (org-mode . 3))`,
+			wantedDiagnostics: []position{
+				{row: 1, col: 12, pos: 42},
+				{row: 3, col: 2, pos: 90},
+			},
+			wantedTotal: 3,
+		},
+		"syntax errors": {
+			input: `(((org-mode . next-line) . 1)
+ ((a . b . c) . 2)
+ ((a . b) . 2 3)
+ ((. b) . 2)
+ ((org-mode . next-line) . 2))`,
+			wantedDiagnostics: []position{
+				{row: 1, col: 9, pos: 39},
+				{row: 2, col: 14, pos: 63},
+				{row: 3, col: 3, pos: 69},
+			},
+			wantedTotal: 3,
+		},
+		"unbalanced": {
+			input:       "(((org-mode . next-line) . 1)\n ((a . b] . 2))",
+			wantedErr:   true,
+			wantedTotal: 1,
+		},
+		"truncated": {
+			input:       "(((org-mode . next-line) . 1)\n ((org-mode . ",
+			wantedErr:   true,
+			wantedTotal: 1,
+		},
+	}
+	for name, tc := range testcases {
+		parser := NewParser(strings.NewReader(tc.input))
+		parser.Recover = true
+		stats, err := parser.Parse()
+		if tc.wantedErr != (err != nil) {
+			t.Errorf("%s: Got error '%v' but wanted error: %t", name, err, tc.wantedErr)
+		}
+		diagnostics := parser.Diagnostics()
+		if len(diagnostics) != len(tc.wantedDiagnostics) {
+			t.Errorf("%s: Got %d diagnostics but wanted %d: %v", name, len(diagnostics), len(tc.wantedDiagnostics), diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			pos := d.(sexp.LexPosError).Position
+			got := position{row: pos.Row(), col: pos.Col(), pos: pos.Offset()}
+			if got != tc.wantedDiagnostics[i] {
+				t.Errorf("%s: diagnostic %d: Got position '%v' but wanted '%v'", name, i, got, tc.wantedDiagnostics[i])
+			}
+		}
+		if got := stats.Total(); got != tc.wantedTotal {
+			t.Errorf("%s: got total %d but wanted %d", name, got, tc.wantedTotal)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	first, err := Parse(strings.NewReader("(((org-mode . next-line) . 3))"))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	second, err := Parse(strings.NewReader("(((org-mode . next-line) . 1) ((text-mode . next-line) . 4))"))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	first.Merge(second)
+
+	if got := first.PairCount(ModeFunc{Mode: "org-mode", Function: "next-line"}); got != 4 {
+		t.Errorf("Got org-mode next-line count %d but wanted 4", got)
+	}
+	if got := first.FuncCount("next-line"); got != 8 {
+		t.Errorf("Got next-line count %d but wanted 8", got)
+	}
+	if got := first.ModeCount("text-mode"); got != 4 {
+		t.Errorf("Got text-mode count %d but wanted 4", got)
+	}
+	if got := second.Total(); got != 5 {
+		t.Errorf("Merge modified its argument. Got total %d but wanted 5", got)
+	}
+}
+
+func TestStableOrder(t *testing.T) {
+	input := `(((text-mode . b) . 2)
+ ((org-mode . c) . 2)
+ ((org-mode . a) . 2)
+ ((c-mode . d) . 5))`
+	stats, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: '%s'", err)
+	}
+	// repeat to catch orders that depend on map iteration
+	for i := 0; i < 20; i++ {
+		funcs := stats.Funcs()
+		wantedFuncs := Countees{{"d", 5}, {"a", 2}, {"b", 2}, {"c", 2}}
+		for j := range funcs {
+			if funcs[j] != wantedFuncs[j] {
+				t.Fatalf("function %d: Got '%v' but wanted '%v'", j, funcs[j], wantedFuncs[j])
+			}
+		}
+		modes := stats.Modes()
+		wantedModes := Countees{{"c-mode", 5}, {"org-mode", 4}, {"text-mode", 2}}
+		for j := range modes {
+			if modes[j] != wantedModes[j] {
+				t.Fatalf("mode %d: Got '%v' but wanted '%v'", j, modes[j], wantedModes[j])
+			}
+		}
+		pairs := stats.Pairs()
+		wantedPairs := []ModeFunc{
+			{Mode: "c-mode", Function: "d"},
+			{Mode: "org-mode", Function: "a"},
+			{Mode: "org-mode", Function: "c"},
+			{Mode: "text-mode", Function: "b"},
+		}
+		for j := range pairs {
+			if pairs[j].ModeFunc != wantedPairs[j] {
+				t.Fatalf("pair %d: Got '%v' but wanted '%v'", j, pairs[j].ModeFunc, wantedPairs[j])
+			}
+		}
+	}
+}
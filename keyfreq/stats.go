@@ -0,0 +1,204 @@
+package keyfreq
+
+import (
+	"sort"
+)
+
+// Stats holds the number of invocations per function and per mode
+type Stats struct {
+	totalFunc map[string]uint64
+	totalMode map[string]uint64
+	totalPair map[ModeFunc]uint64
+	sources   map[ModeFunc]map[string]bool
+	// forms counts the anonymous commands per original form so that the
+	// Writer can hand them back to Emacs unchanged
+	forms map[ModeFunc]map[string]uint64
+}
+
+func NewStats() *Stats {
+	return &Stats{
+		totalFunc: make(map[string]uint64),
+		totalMode: make(map[string]uint64),
+		totalPair: make(map[ModeFunc]uint64),
+		sources:   make(map[ModeFunc]map[string]bool),
+		forms:     make(map[ModeFunc]map[string]uint64),
+	}
+}
+
+func (s *Stats) addSource(mf ModeFunc, source string) {
+	if s.sources[mf] == nil {
+		s.sources[mf] = make(map[string]bool)
+	}
+	s.sources[mf][source] = true
+}
+
+// addForm counts an anonymous command counted as mf by its original form
+func (s *Stats) addForm(mf ModeFunc, form string, count uint64) {
+	if s.forms[mf] == nil {
+		s.forms[mf] = make(map[string]uint64)
+	}
+	s.forms[mf][form] += count
+}
+
+func (s *Stats) add(mf ModeFunc, count uint64) {
+	s.totalFunc[mf.Function] += count
+	s.totalMode[mf.Mode] += count
+	s.totalPair[mf] += count
+}
+
+// Merge adds all counts of other to s
+func (s *Stats) Merge(other *Stats) {
+	for mf, c := range other.totalPair {
+		s.add(mf, c)
+	}
+	for mf, forms := range other.forms {
+		for form, c := range forms {
+			s.addForm(mf, form, c)
+		}
+	}
+	for mf, sources := range other.sources {
+		for source := range sources {
+			s.addSource(mf, source)
+		}
+	}
+}
+
+// Sources returns the sorted source texts of the anonymous commands counted
+// as mf. They are only recorded if the parser keeps them.
+func (s *Stats) Sources(mf ModeFunc) []string {
+	var sources []string
+	for source := range s.sources[mf] {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// FuncCount returns how often function was called in any mode
+func (s *Stats) FuncCount(function string) uint64 {
+	return s.totalFunc[function]
+}
+
+// ModeCount returns how many commands were called in mode
+func (s *Stats) ModeCount(mode string) uint64 {
+	return s.totalMode[mode]
+}
+
+// PairCount returns how often the function was called in the mode
+func (s *Stats) PairCount(mf ModeFunc) uint64 {
+	return s.totalPair[mf]
+}
+
+// Total returns the number of all recorded command invocations
+func (s *Stats) Total() uint64 {
+	var total uint64 = 0
+	for _, c := range s.totalFunc {
+		total += c
+	}
+	return total
+}
+
+// Funcs returns the functions ordered by their count
+func (s *Stats) Funcs() Countees {
+	return sortedCountees(s.totalFunc)
+}
+
+// Modes returns the modes ordered by their count
+func (s *Stats) Modes() Countees {
+	return sortedCountees(s.totalMode)
+}
+
+// ModeFuncs returns the functions called in mode ordered by their count
+func (s *Stats) ModeFuncs(mode string) Countees {
+	funcs := make(map[string]uint64)
+	for mf, c := range s.totalPair {
+		if mf.Mode == mode {
+			funcs[mf.Function] += c
+		}
+	}
+	return sortedCountees(funcs)
+}
+
+// Pairs returns every (mode, function) pair ordered by its count
+func (s *Stats) Pairs() PairCountees {
+	var pairs PairCountees
+	for mf, c := range s.totalPair {
+		pairs = append(pairs, PairCountee{
+			ModeFunc: mf,
+			Count:    c,
+		})
+	}
+	sort.Sort(pairs)
+	return pairs
+}
+
+func sortedCountees(m map[string]uint64) Countees {
+	var countees Countees
+	for k, c := range m {
+		countees = append(countees, Countee{
+			Key:   k,
+			Count: c,
+		})
+	}
+	sort.Sort(countees)
+	return countees
+}
+
+type Countee struct {
+	Key   string
+	Count uint64
+}
+
+type Countees []Countee
+
+func (c Countees) Len() int {
+	return len(c)
+}
+
+// Less orders by descending count. Ties are ordered by key so that the
+// order does not depend on map iteration.
+func (c Countees) Less(i, j int) bool {
+	if c[i].Count != c[j].Count {
+		return c[i].Count > c[j].Count
+	}
+	return c[i].Key < c[j].Key
+}
+
+func (c Countees) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// Total returns the sum of all counts
+func (c Countees) Total() uint64 {
+	var total uint64 = 0
+	for _, countee := range c {
+		total += countee.Count
+	}
+	return total
+}
+
+type PairCountee struct {
+	ModeFunc
+	Count uint64
+}
+
+type PairCountees []PairCountee
+
+func (c PairCountees) Len() int {
+	return len(c)
+}
+
+// Less orders by descending count, ties by mode and function
+func (c PairCountees) Less(i, j int) bool {
+	if c[i].Count != c[j].Count {
+		return c[i].Count > c[j].Count
+	}
+	if c[i].Mode != c[j].Mode {
+		return c[i].Mode < c[j].Mode
+	}
+	return c[i].Function < c[j].Function
+}
+
+func (c PairCountees) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
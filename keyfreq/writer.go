@@ -0,0 +1,88 @@
+package keyfreq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/native-human/go-keyfreq/sexp"
+)
+
+// Writer is the counterpart of Parser. It writes Stats in the alist format
+// saved and read by keyfreq.el:
+//
+//	(((mode . function) . count)
+//	 ((mode . function) . count))
+//
+// Anonymous commands are written in their original form, one entry per
+// form, instead of under their synthetic name. Counts read under the
+// synthetic name itself are kept in an entry of their own.
+type Writer struct {
+	w *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+// Write writes all (mode, function) pairs of stats ordered by mode and
+// function so that the output of equal stats is identical
+func (w *Writer) Write(stats *Stats) error {
+	pairs := stats.Pairs()
+	sort.Sort(byModeFunc(pairs))
+
+	w.w.WriteString("(")
+	first := true
+	entry := func(mode, function string, count uint64) {
+		if !first {
+			w.w.WriteString("\n ")
+		}
+		first = false
+		fmt.Fprintf(w.w, "((%s . %s) . %d)", sexp.EscapeSymbol(mode), function, count)
+	}
+	for _, pair := range pairs {
+		forms := stats.forms[pair.ModeFunc]
+		var formTexts []string
+		rest := pair.Count
+		for form, count := range forms {
+			formTexts = append(formTexts, form)
+			rest -= count
+		}
+		sort.Strings(formTexts)
+		// the rest was counted under the name itself, like a symbol
+		// <lambda> written by another tool
+		if rest > 0 || len(forms) == 0 {
+			entry(pair.Mode, sexp.EscapeSymbol(pair.Function), rest)
+		}
+		for _, form := range formTexts {
+			entry(pair.Mode, form, forms[form])
+		}
+	}
+	w.w.WriteString(")\n")
+	return w.w.Flush()
+}
+
+// Write writes stats to w in the keyfreq.el format
+func Write(w io.Writer, stats *Stats) error {
+	return NewWriter(w).Write(stats)
+}
+
+type byModeFunc PairCountees
+
+func (c byModeFunc) Len() int {
+	return len(c)
+}
+
+func (c byModeFunc) Less(i, j int) bool {
+	if c[i].Mode != c[j].Mode {
+		return c[i].Mode < c[j].Mode
+	}
+	return c[i].Function < c[j].Function
+}
+
+func (c byModeFunc) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
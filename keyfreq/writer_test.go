@@ -0,0 +1,128 @@
+package keyfreq
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/sexp"
+)
+
+// tokens returns the names of the tokens read from r
+func tokens(r io.Reader) []string {
+	var names []string
+	lexer := sexp.NewLexer(r)
+	for lexer.Next() {
+		names = append(names, lexer.Scan().Token().String())
+	}
+	return names
+}
+
+func TestWriter(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		wanted string
+	}{
+		"empty": {
+			input:  "()",
+			wanted: "()\n",
+		},
+		"single": {
+			input:  "(((fundamental-mode . ido-find-file) . 8))",
+			wanted: "(((fundamental-mode . ido-find-file) . 8))\n",
+		},
+		"sorted": {
+			input: "(((text-mode . next-line) . 5) ((org-mode . previous-line) . 2) ((org-mode . next-line) . 3))",
+			wanted: "(((org-mode . next-line) . 3)\n" +
+				" ((org-mode . previous-line) . 2)\n" +
+				" ((text-mode . next-line) . 5))\n",
+		},
+		"escaped": {
+			input:  `(((org-mode . foo\ bar) . 1) ((org-mode . \12) . 2) ((org-mode . \?x) . 3) ((org-mode . a\(b) . 4) ((org-mode . maybe?) . 5))`,
+			wanted: "(((org-mode . \\12) . 2)\n ((org-mode . \\?x) . 3)\n ((org-mode . a\\(b) . 4)\n ((org-mode . foo\\ bar) . 1)\n ((org-mode . maybe?) . 5))\n",
+		},
+		"anonymous": {
+			input: "(((org-mode . (lambda () (interactive)  (foo))) . 2) ((org-mode . [1 11]) . 3) ((org-mode . #[0 \"\\300\" [x] 1]) . 4) ((org-mode . (lambda () (bar))) . 1))",
+			wanted: "(((org-mode . [1 11]) . 3)\n" +
+				" ((org-mode . #[0 \"\\300\" [x] 1]) . 4)\n" +
+				" ((org-mode . (lambda () (bar))) . 1)\n" +
+				" ((org-mode . (lambda () (interactive) (foo))) . 2))\n",
+		},
+		"merged duplicates": {
+			input:  "(((org-mode . next-line) . 3) ((org-mode . next-line) . 4))",
+			wanted: "(((org-mode . next-line) . 7))\n",
+		},
+	}
+	for name, tc := range testcases {
+		stats, err := Parse(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := Write(&b, stats); err != nil {
+			t.Errorf("%s: unexpected error while writing: '%s'", name, err)
+			continue
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	testcases := map[string]string{
+		"basic": "(((fundamental-mode . ido-find-file) . 8))",
+		"several": `(((org-mode . next-line) . 3)
+ ((org-mode . previous-line) . 2)
+ ((text-mode . next-line) . 5)
+ ((c++-mode . c-electric-brace) . 12))`,
+		"escaped": `(((org-mode . foo\ bar) . 1)
+ ((org-mode . \.) . 2)
+ ((<f1> . \1+x) . 3)
+ ((org-mode . \-5) . 4))`,
+		"lambda": `(((org-mode . "\C-a\C-k") . 2)
+ ((org-mode . (closure (t) nil (interactive) (foo))) . 1)
+ ((org-mode . (lambda () (interactive) (forward-line 2))) . 3)
+ ((org-mode . next-line) . 5))`,
+		"lambda symbol": `(((m . <lambda>) . 5)
+ ((m . (lambda () (interactive) 1)) . 3))`,
+	}
+	for name, input := range testcases {
+		stats, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: '%s'", name, err)
+			continue
+		}
+		var b bytes.Buffer
+		if err := Write(&b, stats); err != nil {
+			t.Errorf("%s: unexpected error while writing: '%s'", name, err)
+			continue
+		}
+
+		wanted := tokens(strings.NewReader(input))
+		got := tokens(bytes.NewReader(b.Bytes()))
+		if strings.Join(got, " ") != strings.Join(wanted, " ") {
+			t.Errorf("%s: written file lexes differently. Got %v but wanted %v", name, got, wanted)
+		}
+
+		reread, err := Parse(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Errorf("%s: can't parse written file: '%s'", name, err)
+			continue
+		}
+		for _, pair := range stats.Pairs() {
+			if got := reread.PairCount(pair.ModeFunc); got != pair.Count {
+				t.Errorf("%s: pair '%s' got count %d but wanted %d", name, pair.ModeFunc, got, pair.Count)
+			}
+		}
+		if len(reread.Pairs()) != len(stats.Pairs()) {
+			t.Errorf("%s: got %d pairs but wanted %d", name, len(reread.Pairs()), len(stats.Pairs()))
+		}
+		if !reflect.DeepEqual(reread.forms, stats.forms) {
+			t.Errorf("%s: got anonymous commands %v but wanted %v", name, reread.forms, stats.forms)
+		}
+	}
+}
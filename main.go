@@ -0,0 +1,370 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+type OutMode uint
+
+const (
+	ALL OutMode = iota
+	MODES
+	FUNCTIONS
+	PAIRS
+	BREAKDOWN
+	SEXP
+)
+
+func (om OutMode) String() string {
+	switch om {
+	case ALL:
+		return "ALL"
+	case MODES:
+		return "MODES"
+	case FUNCTIONS:
+		return "FUNCTIONS"
+	case PAIRS:
+		return "PAIRS"
+	case BREAKDOWN:
+		return "BREAKDOWN"
+	case SEXP:
+		return "SEXP"
+	}
+	panic(fmt.Sprintf("unexpected OutMode value '%d'", om))
+}
+
+func OutModeParse(value string) (OutMode, error) {
+	switch value {
+	case "all":
+		return ALL, nil
+	case "modes":
+		return MODES, nil
+	case "functions":
+		return FUNCTIONS, nil
+	case "pairs":
+		return PAIRS, nil
+	case "breakdown":
+		return BREAKDOWN, nil
+	case "sexp":
+		return SEXP, nil
+	default:
+		return ALL, fmt.Errorf("don't know mode '%s'. Valid values are 'all', 'modes', 'functions', 'pairs', 'breakdown', 'sexp'", value)
+	}
+}
+
+type OutFormat uint
+
+const (
+	CSV OutFormat = iota
+	JSON
+	TABLE
+	MARKDOWN
+	HTML
+	SVG
+)
+
+func (of OutFormat) String() string {
+	switch of {
+	case CSV:
+		return "CSV"
+	case JSON:
+		return "JSON"
+	case TABLE:
+		return "TABLE"
+	case MARKDOWN:
+		return "MARKDOWN"
+	case HTML:
+		return "HTML"
+	case SVG:
+		return "SVG"
+	}
+	panic(fmt.Sprintf("unexpected OutFormat value '%d'", of))
+}
+
+func OutFormatParse(value string) (OutFormat, error) {
+	switch value {
+	case "csv":
+		return CSV, nil
+	case "json":
+		return JSON, nil
+	case "table":
+		return TABLE, nil
+	case "markdown":
+		return MARKDOWN, nil
+	case "html":
+		return HTML, nil
+	case "svg":
+		return SVG, nil
+	default:
+		return CSV, fmt.Errorf("don't know format '%s'. Valid values are 'csv', 'json', 'table', 'markdown', 'html', 'svg'", value)
+	}
+}
+
+type SortOrder uint
+
+const (
+	COUNT_DESC SortOrder = iota
+	COUNT_ASC
+	NAME
+	MODE_COUNT
+)
+
+func (so SortOrder) String() string {
+	switch so {
+	case COUNT_DESC:
+		return "COUNT_DESC"
+	case COUNT_ASC:
+		return "COUNT_ASC"
+	case NAME:
+		return "NAME"
+	case MODE_COUNT:
+		return "MODE_COUNT"
+	}
+	panic(fmt.Sprintf("unexpected SortOrder value '%d'", so))
+}
+
+func SortOrderParse(value string) (SortOrder, error) {
+	switch value {
+	case "count":
+		return COUNT_DESC, nil
+	case "count-asc":
+		return COUNT_ASC, nil
+	case "name":
+		return NAME, nil
+	case "mode":
+		return MODE_COUNT, nil
+	default:
+		return COUNT_DESC, fmt.Errorf("don't know sort order '%s'. Valid values are 'count', 'count-asc', 'name', 'mode'", value)
+	}
+}
+
+type Opts struct {
+	inputFilenames []string
+	mode           OutMode
+	format         OutFormat
+	sort           SortOrder
+	bars           bool
+	width          int
+	chartTop       int
+	top            int
+	minCount       uint64
+	minPercent     float64
+	other          bool
+	breakdownTop   int
+	partial        bool
+	recover        bool
+	bySource       bool
+	includeModes   []string
+	excludeModes   []string
+	includeFuncs   []string
+	excludeFuncs   []string
+	output         string
+}
+
+func compilePatterns(patterns []string) ([]keyfreq.Pattern, error) {
+	var compiled []keyfreq.Pattern
+	for _, pattern := range patterns {
+		p, err := keyfreq.CompilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// filter compiles the include and exclude patterns. It returns nil if
+// there are none.
+func (o *Opts) filter() (*keyfreq.Filter, error) {
+	if len(o.includeModes)+len(o.excludeModes)+len(o.includeFuncs)+len(o.excludeFuncs) == 0 {
+		return nil, nil
+	}
+	var f keyfreq.Filter
+	var err error
+	if f.IncludeModes, err = compilePatterns(o.includeModes); err != nil {
+		return nil, err
+	}
+	if f.ExcludeModes, err = compilePatterns(o.excludeModes); err != nil {
+		return nil, err
+	}
+	if f.IncludeFuncs, err = compilePatterns(o.includeFuncs); err != nil {
+		return nil, err
+	}
+	if f.ExcludeFuncs, err = compilePatterns(o.excludeFuncs); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// addInputFlags registers the flags selecting and parsing input files
+func (o *Opts) addInputFlags(flags *flag.FlagSet) {
+	flags.Var((*stringList)(&o.inputFilenames), "i", "input filename, glob pattern, directory or - for stdin. May be repeated (default ~/.emacs.keyfreq)")
+	flags.BoolVar(&o.partial, "partial", false, "print the entries read before a parse error")
+	flags.BoolVar(&o.recover, "recover", false, "skip malformed entries and report them instead of aborting")
+	flags.Var((*stringList)(&o.includeModes), "include-mode", "only count modes matching this glob or /regexp/. May be repeated")
+	flags.Var((*stringList)(&o.excludeModes), "exclude-mode", "don't count modes matching this glob or /regexp/. May be repeated")
+	flags.Var((*stringList)(&o.includeFuncs), "include-func", "only count functions matching this glob or /regexp/. May be repeated")
+	flags.Var((*stringList)(&o.excludeFuncs), "exclude-func", "don't count functions matching this glob or /regexp/. May be repeated")
+}
+
+// addOutputFlag registers the flag selecting the output file
+func (o *Opts) addOutputFlag(flags *flag.FlagSet) {
+	flags.StringVar(&o.output, "o", "", "write the output to this file instead of stdout. The file is replaced atomically")
+}
+
+// finishInputFlags adds the positional arguments to the input files and
+// validates the patterns
+func (o *Opts) finishInputFlags(flags *flag.FlagSet) error {
+	o.inputFilenames = append(o.inputFilenames, flags.Args()...)
+	if len(o.inputFilenames) == 0 {
+		o.inputFilenames = []string{path.Join(os.Getenv("HOME"), ".emacs.keyfreq")}
+	}
+	_, err := o.filter()
+	return err
+}
+
+// readArgs parses the flags of the report command
+func (o *Opts) readArgs(flags *flag.FlagSet, args []string) error {
+	o.addInputFlags(flags)
+	o.addOutputFlag(flags)
+	outMode := flags.String("mode", "all", "specify what to output. Choose between all, modes, functions, pairs, breakdown and sexp (keyfreq.el format)")
+	outFormat := flags.String("format", "csv", "output format of the report. Choose between csv, json, table, markdown, html and svg")
+	sortOrder := flags.String("sort", "count", "order of the rows. Choose between count, count-asc, name and mode (mode, then count)")
+	flags.BoolVar(&o.bars, "bars", false, "add a bar chart column to -format table")
+	flags.IntVar(&o.width, "width", 0, "width of -format table. Defaults to the terminal width, $COLUMNS or 80")
+	flags.IntVar(&o.chartTop, "chart-top", 20, "number of bars drawn per chart by -format svg. 0 draws all")
+	flags.IntVar(&o.top, "top", 0, "only report the N most frequent rows. 0 reports all")
+	flags.Uint64Var(&o.minCount, "min-count", 0, "only report rows called at least this often")
+	flags.Float64Var(&o.minPercent, "min-percent", 0, "only report rows with at least this share in percent")
+	flags.BoolVar(&o.other, "other", false, "sum up the rows dropped by -top, -min-count and -min-percent in an (other) row")
+	flags.IntVar(&o.breakdownTop, "breakdown-top", 10, "number of functions listed per mode in the breakdown. 0 lists all")
+	flags.BoolVar(&o.bySource, "by-source", false, "report every input file separately in a leading column")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := o.finishInputFlags(flags); err != nil {
+		return err
+	}
+
+	var err error
+	o.mode, err = OutModeParse(*outMode)
+	if err != nil {
+		return err
+	}
+	o.format, err = OutFormatParse(*outFormat)
+	if err != nil {
+		return err
+	}
+	o.sort, err = SortOrderParse(*sortOrder)
+	if err != nil {
+		return err
+	}
+	if o.mode == SEXP && o.bySource {
+		return fmt.Errorf("-by-source can't be combined with -mode sexp")
+	}
+	if o.mode == SEXP && o.format != CSV {
+		return fmt.Errorf("-format can't be combined with -mode sexp")
+	}
+	return nil
+}
+
+func printReport(w io.Writer, inputs []input, opts Opts) error {
+	if opts.mode == SEXP {
+		for _, in := range inputs {
+			if err := keyfreq.Write(w, in.stats); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return writeTables(w, buildTables(inputs, opts), opts)
+}
+
+// writeTables renders tables in the format selected by opts
+func writeTables(w io.Writer, tables []Table, opts Opts) error {
+	switch opts.format {
+	case CSV:
+		return writeCSV(w, tables)
+	case JSON:
+		return writeJSON(w, tables)
+	case TABLE:
+		width := opts.width
+		if width <= 0 {
+			width = terminalWidth()
+		}
+		return writeTable(w, tables, opts.bars, width)
+	case MARKDOWN:
+		return writeMarkdown(w, tables)
+	case HTML:
+		return writeHTML(w, tables)
+	case SVG:
+		return writeSVG(w, tables, opts.chartTop)
+	}
+	panic(fmt.Sprintf("Unknown format: %d", opts.format))
+}
+
+// loadInputs parses all input files and returns their merged counts and
+// the counts per file. code is 1 if an input could not be read completely.
+// Nothing must be reported if ok is false.
+func loadInputs(opts Opts, stderr io.Writer) (merged input, sources []input, code int, ok bool) {
+	filenames, err := expandInputs(opts.inputFilenames)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return merged, nil, 1, false
+	}
+
+	filter, err := opts.filter()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return merged, nil, 1, false
+	}
+
+	merged = input{stats: keyfreq.NewStats()}
+	for _, filename := range filenames {
+		stats, err := parseFile(filename, opts, filter, stderr)
+		if err != nil {
+			if !opts.partial {
+				return merged, nil, 1, false
+			}
+			fmt.Fprintf(stderr, "%s: warning: partial output, entries after the error are missing\n", filename)
+			code = 1
+		}
+		if stats == nil {
+			continue
+		}
+		merged.stats.Merge(stats)
+		sources = append(sources, input{name: filename, stats: stats})
+	}
+	return merged, sources, code, true
+}
+
+// run executes the report command with the parsed options and returns the
+// exit code
+func run(opts Opts, stdout, stderr io.Writer) int {
+	merged, sources, code, ok := loadInputs(opts, stderr)
+	if !ok {
+		return code
+	}
+	if !opts.bySource {
+		sources = []input{merged}
+	}
+	err := writeOutput(opts.output, stdout, func(w io.Writer) error {
+		return printReport(w, sources, opts)
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return code
+}
+
+func main() {
+	os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+}
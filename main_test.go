@@ -0,0 +1,719 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func TestOutMode(t *testing.T) {
+	testcases := map[string]struct {
+		input        string
+		wanted       OutMode
+		wantedString string
+	}{
+		"all": {
+			input:        "all",
+			wanted:       ALL,
+			wantedString: "ALL",
+		},
+		"pairs": {
+			input:        "pairs",
+			wanted:       PAIRS,
+			wantedString: "PAIRS",
+		},
+		"breakdown": {
+			input:        "breakdown",
+			wanted:       BREAKDOWN,
+			wantedString: "BREAKDOWN",
+		},
+		"sexp": {
+			input:        "sexp",
+			wanted:       SEXP,
+			wantedString: "SEXP",
+		},
+	}
+	for name, tc := range testcases {
+		om, err := OutModeParse(tc.input)
+		if err != nil {
+			t.Errorf("%s: readArgs returned unexpected error: %s", name, err)
+			continue
+		}
+		if tc.wanted != om {
+			t.Errorf("%s: parsing did not yield correct result. Wanted: '%s' Got: '%s'",
+				name, tc.wanted, om)
+		}
+		toString := tc.wanted.String()
+		if toString != tc.wantedString {
+			t.Errorf("%s: String() did not yield correct result. Wanted: '%s' Got: '%s'",
+				name, tc.wantedString, toString)
+		}
+	}
+}
+
+func TestOutFormat(t *testing.T) {
+	testcases := map[string]struct {
+		input        string
+		wanted       OutFormat
+		wantedString string
+	}{
+		"csv": {
+			input:        "csv",
+			wanted:       CSV,
+			wantedString: "CSV",
+		},
+		"json": {
+			input:        "json",
+			wanted:       JSON,
+			wantedString: "JSON",
+		},
+		"table": {
+			input:        "table",
+			wanted:       TABLE,
+			wantedString: "TABLE",
+		},
+		"markdown": {
+			input:        "markdown",
+			wanted:       MARKDOWN,
+			wantedString: "MARKDOWN",
+		},
+		"html": {
+			input:        "html",
+			wanted:       HTML,
+			wantedString: "HTML",
+		},
+		"svg": {
+			input:        "svg",
+			wanted:       SVG,
+			wantedString: "SVG",
+		},
+	}
+	for name, tc := range testcases {
+		of, err := OutFormatParse(tc.input)
+		if err != nil {
+			t.Errorf("%s: OutFormatParse returned unexpected error: %s", name, err)
+			continue
+		}
+		if tc.wanted != of {
+			t.Errorf("%s: parsing did not yield correct result. Wanted: '%s' Got: '%s'",
+				name, tc.wanted, of)
+		}
+		if toString := of.String(); toString != tc.wantedString {
+			t.Errorf("%s: String() did not yield correct result. Wanted: '%s' Got: '%s'",
+				name, tc.wantedString, toString)
+		}
+	}
+	if _, err := OutFormatParse("xml"); err == nil {
+		t.Errorf("OutFormatParse accepted unknown format 'xml'")
+	}
+}
+
+func TestOpts(t *testing.T) {
+	path := "/home/.emacs.keyfreq"
+	testcases := map[string]struct {
+		input  []string
+		wanted Opts
+	}{
+		"basic": {
+			input: []string{"keyfreq", "-i", path},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"modes": {
+			input: []string{"keyfreq", "-i", path, "-mode", "modes"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           MODES,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"functions": {
+			input: []string{"keyfreq", "-i", path, "-mode", "functions"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           FUNCTIONS,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"pairs": {
+			input: []string{"keyfreq", "-i", path, "-mode", "pairs"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           PAIRS,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"breakdown": {
+			input: []string{"keyfreq", "-i", path, "-mode", "breakdown", "-breakdown-top", "3"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           BREAKDOWN,
+				breakdownTop:   3,
+				chartTop:       20,
+			},
+		},
+		"partial": {
+			input: []string{"keyfreq", "-i", path, "-partial"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+				partial:        true,
+			},
+		},
+		"recover": {
+			input: []string{"keyfreq", "-i", path, "-recover"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+				recover:        true,
+			},
+		},
+		"json": {
+			input: []string{"keyfreq", "-i", path, "-format", "json"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				format:         JSON,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"filters": {
+			input: []string{"keyfreq", "-i", path, "-top", "5", "-min-count", "3", "-min-percent", "0.5", "-other"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+				top:            5,
+				minCount:       3,
+				minPercent:     0.5,
+				other:          true,
+			},
+		},
+		"patterns": {
+			input: []string{"keyfreq", "-i", path,
+				"-include-mode", "org-*", "-include-mode", "/^text/",
+				"-exclude-mode", "minibuffer-inactive-mode",
+				"-include-func", "next-*",
+				"-exclude-func", "self-insert-command"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+				includeModes:   []string{"org-*", "/^text/"},
+				excludeModes:   []string{"minibuffer-inactive-mode"},
+				includeFuncs:   []string{"next-*"},
+				excludeFuncs:   []string{"self-insert-command"},
+			},
+		},
+		"sort": {
+			input: []string{"keyfreq", "-i", path, "-sort", "mode"},
+			wanted: Opts{
+				inputFilenames: []string{path},
+				mode:           ALL,
+				sort:           MODE_COUNT,
+				breakdownTop:   10,
+				chartTop:       20,
+			},
+		},
+		"multiple inputs": {
+			input: []string{"keyfreq", "-i", path, "-i", "/tmp/*.keyfreq", "-by-source"},
+			wanted: Opts{
+				inputFilenames: []string{path, "/tmp/*.keyfreq"},
+				mode:           ALL,
+				breakdownTop:   10,
+				chartTop:       20,
+				bySource:       true,
+			},
+		},
+	}
+	for name, tc := range testcases {
+		var o Opts
+		err := o.readArgs(flag.NewFlagSet("report", flag.ContinueOnError), tc.input[1:])
+		if err != nil {
+			t.Errorf("%s: readArgs returned unexpected error: %s", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.wanted, o) {
+			t.Errorf("%s: Parsing Arguments failed. Wanted '%v'. Got '%v'",
+				name, tc.wanted, o)
+		}
+	}
+}
+
+func TestPairReport(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		wanted string
+	}{
+		"basic": {
+			input: `(((org-mode . next-line) . 3)
+ ((org-mode . previous-line) . 1))`,
+			wanted: "mode,function,count,percent,mode_percent\n" +
+				"org-mode,next-line,3,75.000000,75.000000\n" +
+				"org-mode,previous-line,1,25.000000,25.000000\n",
+		},
+		"two modes": {
+			input: `(((org-mode . next-line) . 3)
+ ((text-mode . next-line) . 1))`,
+			wanted: "mode,function,count,percent,mode_percent\n" +
+				"org-mode,next-line,3,75.000000,100.000000\n" +
+				"text-mode,next-line,1,25.000000,100.000000\n",
+		},
+	}
+	for name, tc := range testcases {
+		stats, err := keyfreq.Parse(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		var b bytes.Buffer
+		tables := buildTables([]input{{stats: stats}}, Opts{mode: PAIRS})
+		if err := writeCSV(&b, tables); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestBreakdownReport(t *testing.T) {
+	content := `(((org-mode . next-line) . 3)
+ ((org-mode . previous-line) . 1)
+ ((org-mode . org-cycle) . 4)
+ ((text-mode . next-line) . 2))`
+	testcases := map[string]struct {
+		top    int
+		wanted string
+	}{
+		"all": {
+			top: 0,
+			wanted: "mode,function,count,mode_percent\n" +
+				"org-mode,org-cycle,4,50.000000\n" +
+				"org-mode,next-line,3,37.500000\n" +
+				"org-mode,previous-line,1,12.500000\n" +
+				"text-mode,next-line,2,100.000000\n",
+		},
+		"top": {
+			top: 1,
+			wanted: "mode,function,count,mode_percent\n" +
+				"org-mode,org-cycle,4,50.000000\n" +
+				"text-mode,next-line,2,100.000000\n",
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		var b bytes.Buffer
+		tables := buildTables([]input{{stats: stats}}, Opts{mode: BREAKDOWN, breakdownTop: tc.top})
+		if err := writeCSV(&b, tables); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "keyfreq")
+	if err != nil {
+		t.Fatalf("can't create temp file: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("can't write temp file: %s", err)
+	}
+	return f.Name()
+}
+
+func TestRun(t *testing.T) {
+	testcases := map[string]struct {
+		input        string
+		partial      bool
+		recover      bool
+		excludeMode  string
+		excludeFunc  string
+		wantedCode   int
+		wantedStdout string
+		wantedStderr string
+	}{
+		"valid": {
+			input:        "(((org-mode . next-line) . 1))",
+			wantedCode:   0,
+			wantedStdout: "key,count,percent\nnext-line,1,100.000000\n",
+			wantedStderr: "",
+		},
+		"truncated": {
+			input:        "(((org-mode . next-line) . 1)\n ((org-mode . ",
+			wantedCode:   1,
+			wantedStdout: "",
+			wantedStderr: ":2:15: expected ')' but got end of file\n ((org-mode . \n              ^\n",
+		},
+		"truncated partial": {
+			input:        "(((org-mode . next-line) . 1)\n ((org-mode . ",
+			partial:      true,
+			wantedCode:   1,
+			wantedStdout: "key,count,percent\nnext-line,1,100.000000\n",
+			wantedStderr: ":2:15: expected ')' but got end of file\n ((org-mode . \n              ^\n",
+		},
+		"exclude": {
+			input:        "(((org-mode . next-line) . 1)\n ((minibuffer-inactive-mode . next-line) . 3)\n ((org-mode . self-insert-command) . 9))",
+			excludeMode:  "minibuffer-*",
+			excludeFunc:  "/^self-insert/",
+			wantedCode:   0,
+			wantedStdout: "key,count,percent\nnext-line,1,100.000000\n",
+			wantedStderr: "",
+		},
+		"recover": {
+			input:        "(((org-mode . next-line) . 1)\n ((org-mode . next-line) . x)\n ((org-mode . next-line) . 3))",
+			recover:      true,
+			wantedCode:   0,
+			wantedStdout: "key,count,percent\nnext-line,4,100.000000\n",
+			wantedStderr: ":2:28: expected number but got 'x' (entry skipped)\n ((org-mode . next-line) . x)\n                           ^\n",
+		},
+	}
+	for name, tc := range testcases {
+		filename := writeTempFile(t, tc.input)
+		defer os.Remove(filename)
+
+		opts := Opts{
+			inputFilenames: []string{filename},
+			mode:           FUNCTIONS,
+			partial:        tc.partial,
+			recover:        tc.recover,
+		}
+		if tc.excludeMode != "" {
+			opts.excludeModes = []string{tc.excludeMode}
+		}
+		if tc.excludeFunc != "" {
+			opts.excludeFuncs = []string{tc.excludeFunc}
+		}
+		var stdout, stderr bytes.Buffer
+		code := run(opts, &stdout, &stderr)
+		if code != tc.wantedCode {
+			t.Errorf("%s: Got exit code %d but wanted %d", name, code, tc.wantedCode)
+		}
+		if got := stdout.String(); got != tc.wantedStdout {
+			t.Errorf("%s: Got stdout '%s' but wanted '%s'", name, got, tc.wantedStdout)
+		}
+		if tc.wantedStderr == "" {
+			if stderr.Len() != 0 {
+				t.Errorf("%s: Got unexpected stderr '%s'", name, stderr.String())
+			}
+		} else if !strings.HasPrefix(stderr.String(), filename+tc.wantedStderr) {
+			t.Errorf("%s: Got stderr '%s' but wanted '%s'", name, stderr.String(), filename+tc.wantedStderr)
+		}
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfreq")
+	if err != nil {
+		t.Fatalf("can't create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.keyfreq", "b.keyfreq", "c.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("can't write temp file: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("can't create sub dir: %s", err)
+	}
+
+	testcases := map[string]struct {
+		input     []string
+		wanted    []string
+		wantedErr bool
+	}{
+		"file": {
+			input:  []string{filepath.Join(dir, "c.txt")},
+			wanted: []string{filepath.Join(dir, "c.txt")},
+		},
+		"glob": {
+			input:  []string{filepath.Join(dir, "*.keyfreq")},
+			wanted: []string{filepath.Join(dir, "a.keyfreq"), filepath.Join(dir, "b.keyfreq")},
+		},
+		"directory": {
+			input: []string{dir},
+			wanted: []string{
+				filepath.Join(dir, "a.keyfreq"),
+				filepath.Join(dir, "b.keyfreq"),
+				filepath.Join(dir, "c.txt"),
+			},
+		},
+		"no match": {
+			input:     []string{filepath.Join(dir, "*.el")},
+			wantedErr: true,
+		},
+		"missing": {
+			input:     []string{filepath.Join(dir, "missing")},
+			wantedErr: true,
+		},
+		"stdin": {
+			input:  []string{"-", filepath.Join(dir, "a.keyfreq")},
+			wanted: []string{"-", filepath.Join(dir, "a.keyfreq")},
+		},
+	}
+	for name, tc := range testcases {
+		got, err := expandInputs(tc.input)
+		if tc.wantedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.wanted) {
+			t.Errorf("%s: Got '%v' but wanted '%v'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestCompressedInput(t *testing.T) {
+	const content = "(((org-mode . next-line) . 3))"
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(content))
+	gw.Close()
+	// bzip2 of the content, the standard library can only decompress
+	bz, _ := base64.StdEncoding.DecodeString("QlpoOTFBWSZTWbzIXu4AAAIZgEBjCAAGp5RAIAAhqNBpo08oKGmmABnwGuLNlQ9DshHoLox5fF3JFOFCQvMhe7g=")
+
+	testcases := map[string]struct {
+		input     []byte
+		wantedErr bool
+	}{
+		"plain": {
+			input: []byte(content),
+		},
+		"gzip": {
+			input: gz.Bytes(),
+		},
+		"bzip2": {
+			input: bz,
+		},
+		"zstd": {
+			input:     []byte{0x28, 0xb5, 0x2f, 0xfd, 0},
+			wantedErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		r, err := decompress(bytes.NewReader(tc.input))
+		if tc.wantedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: can't read: %s", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, content)
+		}
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("(((org-mode . next-line) . 3))"))
+	gw.Close()
+	oldStdin := stdin
+	defer func() { stdin = oldStdin }()
+	stdin = &gz
+
+	var stdout, stderr bytes.Buffer
+	code := runCommand([]string{"-mode", "modes", "-i", "-"}, &stdout, &stderr)
+	if code != 0 {
+		t.Errorf("Got exit code %d: %s", code, stderr.String())
+	}
+	wanted := "key,count,percent\norg-mode,3,100.000000\n"
+	if got := stdout.String(); got != wanted {
+		t.Errorf("Got\n%s\nbut wanted\n%s", got, wanted)
+	}
+}
+
+func TestRunMultipleInputs(t *testing.T) {
+	first := writeTempFile(t, "(((org-mode . next-line) . 3))")
+	defer os.Remove(first)
+	second := writeTempFile(t, "(((org-mode . next-line) . 1)\n ((text-mode . next-line) . 4))")
+	defer os.Remove(second)
+
+	testcases := map[string]struct {
+		mode     OutMode
+		bySource bool
+		wanted   string
+	}{
+		"merged": {
+			mode:   MODES,
+			wanted: "key,count,percent\norg-mode,4,50.000000\ntext-mode,4,50.000000\n",
+		},
+		"merged sexp": {
+			mode:   SEXP,
+			wanted: "(((org-mode . next-line) . 4)\n ((text-mode . next-line) . 4))\n",
+		},
+		"by source": {
+			mode:     MODES,
+			bySource: true,
+			wanted: "source,key,count,percent\n" +
+				first + ",org-mode,3,100.000000\n" +
+				second + ",text-mode,4,80.000000\n" +
+				second + ",org-mode,1,20.000000\n",
+		},
+	}
+	for name, tc := range testcases {
+		opts := Opts{
+			inputFilenames: []string{first, second},
+			mode:           tc.mode,
+			bySource:       tc.bySource,
+		}
+		var stdout, stderr bytes.Buffer
+		if code := run(opts, &stdout, &stderr); code != 0 {
+			t.Errorf("%s: Got exit code %d: %s", name, code, stderr.String())
+		}
+		// modes with equal counts come out in random order
+		got := strings.Split(stdout.String(), "\n")
+		wanted := strings.Split(tc.wanted, "\n")
+		sort.Strings(got)
+		sort.Strings(wanted)
+		if !reflect.DeepEqual(got, wanted) {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, stdout.String(), tc.wanted)
+		}
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	content := `(((org-mode . next-line) . 3)
+ ((text-mode . next-line) . 1))`
+	testcases := map[string]struct {
+		opts   Opts
+		source string
+		wanted string
+	}{
+		"functions": {
+			opts: Opts{mode: FUNCTIONS},
+			wanted: `{
+  "functions": [
+    {
+      "count": 4,
+      "key": "next-line",
+      "percent": 100
+    }
+  ]
+}
+`,
+		},
+		"pairs by source": {
+			opts:   Opts{mode: PAIRS, bySource: true},
+			source: "a.keyfreq",
+			wanted: `{
+  "pairs": [
+    {
+      "count": 3,
+      "function": "next-line",
+      "mode": "org-mode",
+      "mode_percent": 100,
+      "percent": 75,
+      "source": "a.keyfreq"
+    },
+    {
+      "count": 1,
+      "function": "next-line",
+      "mode": "text-mode",
+      "mode_percent": 100,
+      "percent": 25,
+      "source": "a.keyfreq"
+    }
+  ]
+}
+`,
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		var b bytes.Buffer
+		tables := buildTables([]input{{name: tc.source, stats: stats}}, tc.opts)
+		if err := writeJSON(&b, tables); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestCSVReport(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		opts   Opts
+		wanted string
+	}{
+		"all": {
+			input: "(((org-mode . next-line) . 3))",
+			opts:  Opts{mode: ALL},
+			wanted: "section,key,count,percent\n" +
+				"functions,next-line,3,100.000000\n" +
+				"modes,org-mode,3,100.000000\n",
+		},
+		"quoting": {
+			input: "(((org-mode . next-line) . 3))",
+			opts:  Opts{mode: FUNCTIONS, bySource: true},
+			wanted: "source,key,count,percent\n" +
+				"\"a,\"\"b\"\"\",next-line,3,100.000000\n",
+		},
+	}
+	for name, tc := range testcases {
+		stats, err := keyfreq.Parse(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		var b bytes.Buffer
+		tables := buildTables([]input{{name: `a,"b"`, stats: stats}}, tc.opts)
+		if err := writeCSV(&b, tables); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes the markdown syntax and the HTML characters, which
+// would turn names like <lambda> into tags
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", "|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`",
+	"<", "&lt;", ">", "&gt;", "&", "&amp;",
+)
+
+// writeMarkdown renders every table as a GitHub flavoured markdown table
+// below a heading
+func writeMarkdown(w io.Writer, tables []Table) error {
+	for i, table := range tables {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "## %s\n\n", table.Title); err != nil {
+			return err
+		}
+
+		header := make([]string, len(table.Columns))
+		align := make([]string, len(table.Columns))
+		for j, column := range table.Columns {
+			header[j] = markdownEscaper.Replace(column)
+			align[j] = "---"
+			if isNumeric(column) {
+				align[j] = "--:"
+			}
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n|%s|\n", strings.Join(header, " | "), strings.Join(align, "|")); err != nil {
+			return err
+		}
+
+		for _, row := range table.Rows {
+			cells := make([]string, len(table.Columns))
+			for j, column := range table.Columns {
+				cells[j] = markdownEscaper.Replace(formatCell(row.value(column)))
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
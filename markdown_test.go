@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func TestMarkdownReport(t *testing.T) {
+	content := `(((org-mode . next-line) . 3000)
+ ((org-mode . org-cycle) . 1000))`
+	testcases := map[string]struct {
+		opts   Opts
+		wanted string
+	}{
+		"functions": {
+			opts: Opts{mode: FUNCTIONS, format: MARKDOWN},
+			wanted: "## Funcs\n\n" +
+				"| key | count | percent |\n" +
+				"|---|--:|--:|\n" +
+				"| next-line | 3,000 | 75.00% |\n" +
+				"| org-cycle | 1,000 | 25.00% |\n",
+		},
+		"escaping": {
+			opts: Opts{mode: MODES, format: MARKDOWN, bySource: true},
+			wanted: "## Modes\n\n" +
+				"| source | key | count | percent |\n" +
+				"|---|---|--:|--:|\n" +
+				"| a\\|b\\_c | org-mode | 4,000 | 100.00% |\n",
+		},
+		"all": {
+			opts: Opts{mode: ALL, format: MARKDOWN},
+			wanted: "## Funcs\n\n" +
+				"| key | count | percent |\n" +
+				"|---|--:|--:|\n" +
+				"| next-line | 3,000 | 75.00% |\n" +
+				"| org-cycle | 1,000 | 25.00% |\n" +
+				"\n" +
+				"## Modes\n\n" +
+				"| key | count | percent |\n" +
+				"|---|--:|--:|\n" +
+				"| org-mode | 4,000 | 100.00% |\n" +
+				"\n" +
+				"## Breakdown\n\n" +
+				"| mode | function | count | mode\\_percent |\n" +
+				"|---|---|--:|--:|\n" +
+				"| org-mode | next-line | 3,000 | 75.00% |\n" +
+				"| org-mode | org-cycle | 1,000 | 25.00% |\n",
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		var b bytes.Buffer
+		tables := buildTables([]input{{name: "a|b_c", stats: stats}}, tc.opts)
+		if err := writeMarkdown(&b, tables); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got\n%s\nbut wanted\n%s", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	content := `(((<f1> . (lambda () (interactive))) . 4)
+ ((org-mode . a&b\\c) . 1))`
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wanted := "## Pairs\n\n" +
+		"| mode | function | count | percent | mode\\_percent |\n" +
+		"|---|---|--:|--:|--:|\n" +
+		"| &lt;f1&gt; | &lt;lambda&gt; | 4 | 80.00% | 100.00% |\n" +
+		"| org-mode | a&amp;b\\\\c | 1 | 20.00% | 100.00% |\n"
+	var b bytes.Buffer
+	tables := buildTables([]input{{stats: stats}}, Opts{mode: PAIRS, format: MARKDOWN})
+	if err := writeMarkdown(&b, tables); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := b.String(); got != wanted {
+		t.Errorf("Got\n%s\nbut wanted\n%s", got, wanted)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// writeOutput calls write with stdout if path is empty. Otherwise the
+// output is written to a temporary file next to path which then replaces
+// path, so readers never see a half-written file. Missing parent
+// directories are created. The mode of an existing file is kept, new files
+// are created like by a shell redirection.
+func writeOutput(path string, stdout io.Writer, write func(io.Writer) error) error {
+	if path == "" {
+		return write(stdout)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := createTemp(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// removing fails harmlessly once the file is renamed
+	defer os.Remove(tmp.Name())
+
+	if info, err := os.Stat(path); err == nil {
+		if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	// the data must be on disk before the rename, otherwise a crash can
+	// leave an empty report behind
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// createTemp creates a new file in dir whose name starts with prefix. Unlike
+// ioutil.TempFile it uses the mode 0666 restricted by the umask.
+func createTemp(dir, prefix string) (*os.File, error) {
+	for i := 0; i < 100; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, fmt.Errorf("can't create a temporary file in %s", dir)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfreq")
+	if err != nil {
+		t.Fatalf("can't create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	existing := filepath.Join(dir, "existing.csv")
+	kept := filepath.Join(dir, "kept.csv")
+	for _, path := range []string{existing, kept} {
+		if err := ioutil.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatalf("can't write file: %s", err)
+		}
+	}
+
+	testcases := map[string]struct {
+		path         string
+		writeErr     error
+		wantedErr    bool
+		wantedStdout string
+		wantedFile   string
+	}{
+		"stdout": {
+			wantedStdout: "new\n",
+		},
+		"new file": {
+			path:       filepath.Join(dir, "new.csv"),
+			wantedFile: "new\n",
+		},
+		"parent directories": {
+			path:       filepath.Join(dir, "reports", "daily", "new.csv"),
+			wantedFile: "new\n",
+		},
+		"replace": {
+			path:       existing,
+			wantedFile: "new\n",
+		},
+		"failed write keeps old file": {
+			path:       kept,
+			writeErr:   fmt.Errorf("broken"),
+			wantedErr:  true,
+			wantedFile: "old\n",
+		},
+	}
+	for name, tc := range testcases {
+		var stdout bytes.Buffer
+		err := writeOutput(tc.path, &stdout, func(w io.Writer) error {
+			io.WriteString(w, "new\n")
+			return tc.writeErr
+		})
+		if (err != nil) != tc.wantedErr {
+			t.Errorf("%s: Got error '%v'", name, err)
+		}
+		if got := stdout.String(); got != tc.wantedStdout {
+			t.Errorf("%s: Got stdout '%s' but wanted '%s'", name, got, tc.wantedStdout)
+		}
+		if tc.path == "" {
+			continue
+		}
+		got, err := ioutil.ReadFile(tc.path)
+		if err != nil {
+			t.Errorf("%s: can't read output: %s", name, err)
+		}
+		if string(got) != tc.wantedFile {
+			t.Errorf("%s: Got file '%s' but wanted '%s'", name, got, tc.wantedFile)
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(tc.path), ".*.tmp*"))
+		if len(leftovers) != 0 {
+			t.Errorf("%s: temporary files left: %v", name, leftovers)
+		}
+	}
+}
+
+func TestWriteOutputMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfreq")
+	if err != nil {
+		t.Fatalf("can't create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a file created like by a shell redirection has the mode wanted for
+	// new files
+	reference := filepath.Join(dir, "reference")
+	f, err := os.OpenFile(reference, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("can't create file: %s", err)
+	}
+	f.Close()
+	info, err := os.Stat(reference)
+	if err != nil {
+		t.Fatalf("can't stat file: %s", err)
+	}
+	private := filepath.Join(dir, "private.csv")
+	if err := ioutil.WriteFile(private, []byte("old\n"), 0600); err != nil {
+		t.Fatalf("can't write file: %s", err)
+	}
+	if err := os.Chmod(private, 0600); err != nil {
+		t.Fatalf("can't change mode: %s", err)
+	}
+
+	testcases := map[string]struct {
+		path       string
+		wantedMode os.FileMode
+	}{
+		"new file": {
+			path:       filepath.Join(dir, "new.csv"),
+			wantedMode: info.Mode().Perm(),
+		},
+		"existing file": {
+			path:       private,
+			wantedMode: 0600,
+		},
+	}
+	for name, tc := range testcases {
+		err := writeOutput(tc.path, nil, func(w io.Writer) error {
+			_, err := io.WriteString(w, "new\n")
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		info, err := os.Stat(tc.path)
+		if err != nil {
+			t.Errorf("%s: can't stat output: %s", name, err)
+			continue
+		}
+		if got := info.Mode().Perm(); got != tc.wantedMode {
+			t.Errorf("%s: Got mode %v but wanted %v", name, got, tc.wantedMode)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// column names shared by all output formats
+const (
+	colSource      = "source"
+	colKey         = "key"
+	colMode        = "mode"
+	colFunction    = "function"
+	colCount       = "count"
+	colPercent     = "percent"
+	colModePercent = "mode_percent"
+	colOld         = "old"
+	colNew         = "new"
+	colChange      = "change"
+	colRelative    = "relative"
+)
+
+// Row is one line of a report table. Key is the function or mode name of
+// the single key tables. Percent is the share of all calls of the source,
+// ModePercent the share of the calls within the mode. Old, New, Change and
+// Relative describe the difference between two snapshots.
+type Row struct {
+	Source      string
+	Key         string
+	Mode        string
+	Function    string
+	Count       uint64
+	Percent     float64
+	ModePercent float64
+	Old         uint64
+	New         uint64
+	Change      int64
+	Relative    float64
+}
+
+func (r Row) value(column string) interface{} {
+	switch column {
+	case colSource:
+		return r.Source
+	case colKey:
+		return r.Key
+	case colMode:
+		return r.Mode
+	case colFunction:
+		return r.Function
+	case colCount:
+		return r.Count
+	case colPercent:
+		return r.Percent
+	case colModePercent:
+		return r.ModePercent
+	case colOld:
+		return r.Old
+	case colNew:
+		return r.New
+	case colChange:
+		return r.Change
+	case colRelative:
+		// there is no relative change for new keys
+		if r.Old == 0 {
+			return nil
+		}
+		return r.Relative
+	}
+	panic(fmt.Sprintf("unexpected column '%s'", column))
+}
+
+// Table is a format independent report. Every format renders the values
+// of Columns for each row.
+type Table struct {
+	Name    string
+	Title   string
+	Columns []string
+	Rows    []Row
+}
+
+// percent returns the share of count in total or 0 if there are no calls
+// at all
+func percent(count, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100.0 * float64(count) / float64(total)
+}
+
+func funcRows(in input) []Row {
+	var rows []Row
+	total := in.stats.Total()
+	for _, countee := range in.stats.Funcs() {
+		rows = append(rows, Row{
+			Source:   in.name,
+			Key:      countee.Key,
+			Function: countee.Key,
+			Count:    countee.Count,
+			Percent:  percent(countee.Count, total),
+		})
+	}
+	return rows
+}
+
+func modeRows(in input) []Row {
+	var rows []Row
+	total := in.stats.Total()
+	for _, countee := range in.stats.Modes() {
+		rows = append(rows, Row{
+			Source:  in.name,
+			Key:     countee.Key,
+			Mode:    countee.Key,
+			Count:   countee.Count,
+			Percent: percent(countee.Count, total),
+		})
+	}
+	return rows
+}
+
+func pairRows(in input) []Row {
+	var rows []Row
+	total := in.stats.Total()
+	for _, pair := range in.stats.Pairs() {
+		rows = append(rows, Row{
+			Source:      in.name,
+			Mode:        pair.Mode,
+			Function:    pair.Function,
+			Count:       pair.Count,
+			Percent:     percent(pair.Count, total),
+			ModePercent: percent(pair.Count, in.stats.ModeCount(pair.Mode)),
+		})
+	}
+	return rows
+}
+
+// breakdownRows lists the functions of every mode, filtered per mode
+func breakdownRows(in input, filter rowFilter) []Row {
+	var rows []Row
+	total := in.stats.Total()
+	modes := in.stats.Modes()
+	switch filter.order {
+	case COUNT_ASC:
+		sort.SliceStable(modes, func(i, j int) bool { return modes[i].Count < modes[j].Count })
+	case NAME, MODE_COUNT:
+		sort.SliceStable(modes, func(i, j int) bool { return modes[i].Key < modes[j].Key })
+	}
+	for _, mode := range modes {
+		var modeRows []Row
+		for _, countee := range in.stats.ModeFuncs(mode.Key) {
+			modeRows = append(modeRows, Row{
+				Source:      in.name,
+				Mode:        mode.Key,
+				Function:    countee.Key,
+				Count:       countee.Count,
+				Percent:     percent(countee.Count, total),
+				ModePercent: percent(countee.Count, mode.Count),
+			})
+		}
+		rows = append(rows, filter.apply(modeRows, modePercentOf)...)
+	}
+	return rows
+}
+
+const otherKey = "(other)"
+
+// rowFilter drops the rows below the thresholds and beyond the top rows.
+// Zero values disable a limit. The remaining rows are sorted by order. With
+// other the dropped rows are summed up in a trailing "(other)" row so that
+// the percentages still add up to 100.
+type rowFilter struct {
+	top        int
+	minCount   uint64
+	minPercent float64
+	other      bool
+	order      SortOrder
+}
+
+func percentOf(r Row) float64 {
+	return r.Percent
+}
+
+func modePercentOf(r Row) float64 {
+	return r.ModePercent
+}
+
+// apply filters rows ordered by count. share returns the percentage
+// compared to minPercent.
+func (f rowFilter) apply(rows []Row, share func(Row) float64) []Row {
+	var kept, dropped []Row
+	for _, row := range rows {
+		if row.Count < f.minCount || share(row) < f.minPercent || (f.top > 0 && len(kept) >= f.top) {
+			dropped = append(dropped, row)
+		} else {
+			kept = append(kept, row)
+		}
+	}
+	sortRows(kept, f.order)
+	if !f.other || len(dropped) == 0 {
+		return kept
+	}
+	return append(kept, otherRow(dropped))
+}
+
+// lessName orders rows alphabetically by their text fields
+func lessName(a, b Row) bool {
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	if a.Key != b.Key {
+		return a.Key < b.Key
+	}
+	if a.Mode != b.Mode {
+		return a.Mode < b.Mode
+	}
+	return a.Function < b.Function
+}
+
+// sortRows sorts rows by order. Ties are broken by name so that the output
+// is the same on every run.
+func sortRows(rows []Row, order SortOrder) {
+	var less func(a, b Row) bool
+	switch order {
+	case COUNT_DESC:
+		less = func(a, b Row) bool {
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return lessName(a, b)
+		}
+	case COUNT_ASC:
+		less = func(a, b Row) bool {
+			if a.Count != b.Count {
+				return a.Count < b.Count
+			}
+			return lessName(a, b)
+		}
+	case NAME:
+		less = lessName
+	case MODE_COUNT:
+		less = func(a, b Row) bool {
+			if a.Mode != b.Mode {
+				return a.Mode < b.Mode
+			}
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return lessName(a, b)
+		}
+	default:
+		panic(fmt.Sprintf("Unknown sort order: %d", order))
+	}
+	sort.Slice(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+}
+
+// otherRow sums up rows. The names become "(other)" except for the mode
+// of tables listing functions per mode if all rows share it.
+func otherRow(rows []Row) Row {
+	other := rows[0]
+	for _, row := range rows[1:] {
+		if row.Source != other.Source {
+			other.Source = otherKey
+		}
+		if row.Mode != other.Mode {
+			other.Mode = otherKey
+		}
+		other.Count += row.Count
+		other.Percent += row.Percent
+		other.ModePercent += row.ModePercent
+	}
+	if other.Key != "" {
+		other.Key = otherKey
+		if other.Mode != "" {
+			other.Mode = otherKey
+		}
+	}
+	if other.Function != "" {
+		other.Function = otherKey
+	}
+	return other
+}
+
+func newTable(name, title string, inputs []input, bySource bool, columns []string, rows func(input) []Row) Table {
+	table := Table{
+		Name:    name,
+		Title:   title,
+		Columns: columns,
+	}
+	if bySource {
+		table.Columns = append([]string{colSource}, columns...)
+	}
+	for _, in := range inputs {
+		table.Rows = append(table.Rows, rows(in)...)
+	}
+	return table
+}
+
+// filtered applies filter to the rows of every input
+func filtered(rows func(input) []Row, filter rowFilter) func(input) []Row {
+	return func(in input) []Row {
+		return filter.apply(rows(in), percentOf)
+	}
+}
+
+// buildTables returns the tables selected by opts.mode
+func buildTables(inputs []input, opts Opts) []Table {
+	filter := rowFilter{
+		top:        opts.top,
+		minCount:   opts.minCount,
+		minPercent: opts.minPercent,
+		other:      opts.other,
+		order:      opts.sort,
+	}
+	// -top overrides the default number of functions per mode
+	breakdownFilter := filter
+	if breakdownFilter.top <= 0 {
+		breakdownFilter.top = opts.breakdownTop
+	}
+
+	funcs := func() Table {
+		return newTable("functions", "Funcs", inputs, opts.bySource,
+			[]string{colKey, colCount, colPercent}, filtered(funcRows, filter))
+	}
+	modes := func() Table {
+		return newTable("modes", "Modes", inputs, opts.bySource,
+			[]string{colKey, colCount, colPercent}, filtered(modeRows, filter))
+	}
+	breakdown := func() Table {
+		return newTable("breakdown", "Breakdown", inputs, opts.bySource,
+			[]string{colMode, colFunction, colCount, colModePercent},
+			func(in input) []Row { return breakdownRows(in, breakdownFilter) })
+	}
+
+	switch opts.mode {
+	case ALL:
+		// the document formats have room for tables with different columns
+		if opts.format == MARKDOWN || opts.format == HTML {
+			return []Table{funcs(), modes(), breakdown()}
+		}
+		return []Table{funcs(), modes()}
+	case MODES:
+		return []Table{modes()}
+	case FUNCTIONS:
+		return []Table{funcs()}
+	case PAIRS:
+		return []Table{newTable("pairs", "Pairs", inputs, opts.bySource,
+			[]string{colMode, colFunction, colCount, colPercent, colModePercent}, filtered(pairRows, filter))}
+	case BREAKDOWN:
+		return []Table{breakdown()}
+	}
+	panic(fmt.Sprintf("Unknown mode: %d", opts.mode))
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func TestRowFilter(t *testing.T) {
+	rows := []Row{
+		{Key: "a", Function: "a", Count: 50, Percent: 50},
+		{Key: "b", Function: "b", Count: 30, Percent: 30},
+		{Key: "c", Function: "c", Count: 15, Percent: 15},
+		{Key: "d", Function: "d", Count: 5, Percent: 5},
+	}
+	testcases := map[string]struct {
+		filter rowFilter
+		wanted []Row
+	}{
+		"none": {
+			filter: rowFilter{},
+			wanted: rows,
+		},
+		"top": {
+			filter: rowFilter{top: 2},
+			wanted: rows[:2],
+		},
+		"min count": {
+			filter: rowFilter{minCount: 15},
+			wanted: rows[:3],
+		},
+		"min percent": {
+			filter: rowFilter{minPercent: 30},
+			wanted: rows[:2],
+		},
+		"top and min count": {
+			filter: rowFilter{top: 3, minCount: 30},
+			wanted: rows[:2],
+		},
+		"other": {
+			filter: rowFilter{top: 2, other: true},
+			wanted: []Row{
+				rows[0],
+				rows[1],
+				{Key: "(other)", Function: "(other)", Count: 20, Percent: 20},
+			},
+		},
+		"nothing dropped": {
+			filter: rowFilter{top: 10, other: true},
+			wanted: rows,
+		},
+	}
+	for name, tc := range testcases {
+		got := tc.filter.apply(rows, percentOf)
+		if !reflect.DeepEqual(got, tc.wanted) {
+			t.Errorf("%s: Got '%v' but wanted '%v'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestZeroCounts(t *testing.T) {
+	stats, err := keyfreq.Parse(strings.NewReader("(((a . b) . 0))"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tables := buildTables([]input{{stats: stats}}, Opts{mode: ALL, format: JSON})
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			if row.Percent != 0 || row.ModePercent != 0 {
+				t.Errorf("%s: Got percentages %v and %v for %s but wanted 0", table.Title, row.Percent, row.ModePercent, row.Key)
+			}
+		}
+	}
+	var b bytes.Buffer
+	if err := writeJSON(&b, tables); err != nil {
+		t.Errorf("unexpected error while writing json: %s", err)
+	}
+}
+
+func TestFilteredTables(t *testing.T) {
+	content := `(((org-mode . next-line) . 6)
+ ((org-mode . org-cycle) . 3)
+ ((org-mode . previous-line) . 1)
+ ((text-mode . next-line) . 30))`
+	testcases := map[string]struct {
+		opts   Opts
+		wanted []Row
+	}{
+		"breakdown other": {
+			opts: Opts{mode: BREAKDOWN, top: 1, other: true},
+			wanted: []Row{
+				{Mode: "text-mode", Function: "next-line", Count: 30, Percent: 75, ModePercent: 100},
+				{Mode: "org-mode", Function: "next-line", Count: 6, Percent: 15, ModePercent: 60},
+				{Mode: "org-mode", Function: "(other)", Count: 4, Percent: 10, ModePercent: 40},
+			},
+		},
+		"breakdown default top": {
+			opts: Opts{mode: BREAKDOWN, breakdownTop: 2, minCount: 2},
+			wanted: []Row{
+				{Mode: "text-mode", Function: "next-line", Count: 30, Percent: 75, ModePercent: 100},
+				{Mode: "org-mode", Function: "next-line", Count: 6, Percent: 15, ModePercent: 60},
+				{Mode: "org-mode", Function: "org-cycle", Count: 3, Percent: 7.5, ModePercent: 30},
+			},
+		},
+		"pairs min percent": {
+			opts: Opts{mode: PAIRS, minPercent: 10, other: true},
+			wanted: []Row{
+				{Mode: "text-mode", Function: "next-line", Count: 30, Percent: 75, ModePercent: 100},
+				{Mode: "org-mode", Function: "next-line", Count: 6, Percent: 15, ModePercent: 60},
+				{Mode: "org-mode", Function: "(other)", Count: 4, Percent: 10, ModePercent: 40},
+			},
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		tables := buildTables([]input{{stats: stats}}, tc.opts)
+		if len(tables) != 1 {
+			t.Errorf("%s: Got %d tables but wanted 1", name, len(tables))
+			continue
+		}
+		if !reflect.DeepEqual(tables[0].Rows, tc.wanted) {
+			t.Errorf("%s: Got '%v' but wanted '%v'", name, tables[0].Rows, tc.wanted)
+		}
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := []Row{
+		{Mode: "text-mode", Function: "b", Count: 2},
+		{Mode: "org-mode", Function: "c", Count: 2},
+		{Mode: "org-mode", Function: "a", Count: 1},
+		{Mode: "c-mode", Function: "d", Count: 5},
+	}
+	testcases := map[string]struct {
+		order  SortOrder
+		wanted []Row
+	}{
+		"count": {
+			order:  COUNT_DESC,
+			wanted: []Row{rows[3], rows[1], rows[0], rows[2]},
+		},
+		"count ascending": {
+			order:  COUNT_ASC,
+			wanted: []Row{rows[2], rows[1], rows[0], rows[3]},
+		},
+		"name": {
+			order:  NAME,
+			wanted: []Row{rows[3], rows[2], rows[1], rows[0]},
+		},
+		"mode": {
+			order:  MODE_COUNT,
+			wanted: []Row{rows[3], rows[1], rows[2], rows[0]},
+		},
+	}
+	for name, tc := range testcases {
+		got := append([]Row(nil), rows...)
+		sortRows(got, tc.order)
+		if !reflect.DeepEqual(got, tc.wanted) {
+			t.Errorf("%s: Got '%v' but wanted '%v'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestSortedTables(t *testing.T) {
+	content := `(((org-mode . next-line) . 2)
+ ((org-mode . org-cycle) . 2)
+ ((org-mode . previous-line) . 1)
+ ((text-mode . next-line) . 9))`
+	testcases := map[string]struct {
+		opts   Opts
+		wanted []string
+	}{
+		"functions by name with other": {
+			opts:   Opts{mode: FUNCTIONS, sort: NAME, top: 2, other: true},
+			wanted: []string{"next-line", "org-cycle", "(other)"},
+		},
+		"functions ascending": {
+			opts:   Opts{mode: FUNCTIONS, sort: COUNT_ASC},
+			wanted: []string{"previous-line", "org-cycle", "next-line"},
+		},
+		"breakdown ascending": {
+			opts:   Opts{mode: BREAKDOWN, sort: COUNT_ASC},
+			wanted: []string{"org-mode / previous-line", "org-mode / next-line", "org-mode / org-cycle", "text-mode / next-line"},
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		tables := buildTables([]input{{stats: stats}}, tc.opts)
+		var got []string
+		for _, row := range tables[0].Rows {
+			got = append(got, rowLabel(tables[0], row))
+		}
+		if !reflect.DeepEqual(got, tc.wanted) {
+			t.Errorf("%s: Got '%v' but wanted '%v'", name, got, tc.wanted)
+		}
+	}
+}
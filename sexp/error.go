@@ -0,0 +1,127 @@
+package sexp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PosError interface {
+	error
+	GetRow() uint
+	GetCol() uint
+}
+
+// LexPosError is an error at a position of the input. Error formats it like
+// GNU tools as file:line:col: message with 1-based line and column, which
+// Emacs' compilation-mode can jump to.
+type LexPosError struct {
+	Position
+	// Filename is the name of the input. It is empty unless set by InFile.
+	Filename string
+	// Expected and Found describe an unexpected lexeme as they appear in the
+	// message, e.g. "symbol '.'" and "'x'". Expected is empty if anything
+	// but Found would do, both are empty for other errors.
+	Expected string
+	Found    string
+
+	msg string
+}
+
+func (e LexPosError) GetRow() uint {
+	return e.Position.row
+}
+
+func (e LexPosError) GetCol() uint {
+	return e.Position.col
+}
+
+// Msg returns the message without the location
+func (e LexPosError) Msg() string {
+	return e.msg
+}
+
+func (e LexPosError) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line(), e.Column(), e.msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line(), e.Column(), e.msg)
+}
+
+// InFile returns the error located in the input filename
+func (e LexPosError) InFile(filename string) LexPosError {
+	e.Filename = filename
+	return e
+}
+
+// excerptWidth is the maximum number of runes of the source line shown by
+// Excerpt
+const excerptWidth = 80
+
+// Excerpt returns the line of src the error is in and a line with a caret
+// under the column of the error. Lines longer than excerptWidth are cut
+// around the column. It returns an empty string if src has no such line.
+func (e LexPosError) Excerpt(src []byte) string {
+	lines := strings.Split(string(src), "\n")
+	if int(e.row) >= len(lines) {
+		return ""
+	}
+	line := []rune(strings.TrimSuffix(lines[e.row], "\r"))
+	col := int(e.col)
+	if col > len(line) {
+		col = len(line)
+	}
+
+	var prefix, suffix string
+	if len(line) > excerptWidth {
+		start := col - excerptWidth/2
+		if start < 0 {
+			start = 0
+		}
+		end := start + excerptWidth
+		if end > len(line) {
+			end = len(line)
+			start = end - excerptWidth
+		}
+		if start > 0 {
+			prefix = "..."
+		}
+		if end < len(line) {
+			suffix = "..."
+		}
+		line = line[start:end]
+		col -= start
+	}
+
+	// keep tabs so that the caret lines up with the source
+	var caret strings.Builder
+	caret.WriteString(strings.Repeat(" ", len(prefix)))
+	for _, r := range line[:col] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	return prefix + string(line) + suffix + "\n" + caret.String() + "\n"
+}
+
+func PosErrorf(pos Position, msg string, args ...interface{}) LexPosError {
+	var err LexPosError
+	err.Position = pos
+	err.msg = fmt.Sprintf(msg, args...)
+	return err
+}
+
+// Unexpected returns the error for found at pos where expected was
+// expected. The message is "expected <expected> but got <found>" or
+// "unexpected <found>" without expected.
+func Unexpected(pos Position, expected, found string) LexPosError {
+	err := PosErrorf(pos, "unexpected %s", found)
+	if expected != "" {
+		err = PosErrorf(pos, "expected %s but got %s", expected, found)
+	}
+	err.Expected = expected
+	err.Found = found
+	return err
+}
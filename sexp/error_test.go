@@ -0,0 +1,87 @@
+package sexp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPosErrorFormat(t *testing.T) {
+	pos := Position{pos: 12, row: 1, col: 4}
+	testcases := map[string]struct {
+		err    LexPosError
+		wanted string
+	}{
+		"no file": {
+			err:    PosErrorf(pos, "bad %s", "thing"),
+			wanted: "2:5: bad thing",
+		},
+		"file": {
+			err:    PosErrorf(pos, "bad thing").InFile("a.keyfreq"),
+			wanted: "a.keyfreq:2:5: bad thing",
+		},
+		"expected": {
+			err:    Unexpected(pos, "number", "'x'"),
+			wanted: "2:5: expected number but got 'x'",
+		},
+		"unexpected": {
+			err:    Unexpected(pos, "", "end of file"),
+			wanted: "2:5: unexpected end of file",
+		},
+	}
+	for name, tc := range testcases {
+		if got := tc.err.Error(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+
+	err := Unexpected(pos, "number", "'x'")
+	if err.Expected != "number" || err.Found != "'x'" || err.Msg() != "expected number but got 'x'" {
+		t.Errorf("Got fields %q, %q and message %q", err.Expected, err.Found, err.Msg())
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("a", 100) + " x " + strings.Repeat("b", 100)
+	testcases := map[string]struct {
+		src    string
+		pos    Position
+		wanted string
+	}{
+		"first line": {
+			src:    "(a . x)\n(b . 1)",
+			pos:    Position{row: 0, col: 5},
+			wanted: "(a . x)\n     ^\n",
+		},
+		"tabs and runes": {
+			src:    "(a\n\t(ä . x))\r\n",
+			pos:    Position{row: 1, col: 6},
+			wanted: "\t(ä . x))\n\t     ^\n",
+		},
+		"end of line": {
+			src:    "(a . ",
+			pos:    Position{row: 0, col: 9},
+			wanted: "(a . \n     ^\n",
+		},
+		"long line": {
+			src:    long,
+			pos:    Position{row: 0, col: 101},
+			wanted: "..." + long[61:141] + "...\n" + strings.Repeat(" ", 43) + "^\n",
+		},
+		"long line start": {
+			src:    long,
+			pos:    Position{row: 0, col: 2},
+			wanted: long[:80] + "...\n  ^\n",
+		},
+		"no such line": {
+			src:    "(a)",
+			pos:    Position{row: 3, col: 0},
+			wanted: "",
+		},
+	}
+	for name, tc := range testcases {
+		got := PosErrorf(tc.pos, "bad").Excerpt([]byte(tc.src))
+		if got != tc.wanted {
+			t.Errorf("%s: Got\n%q\nbut wanted\n%q", name, got, tc.wanted)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package sexp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// escapeError is an invalid escape sequence at the rune offset of its
+// backslash
+type escapeError struct {
+	offset int
+	msg    string
+}
+
+func (e escapeError) Error() string {
+	return e.msg
+}
+
+// unescapeString returns the value of the string literal text s without the
+// surrounding quotes. It understands the escape sequences of the Emacs
+// reader: character names like \n and \e, octal, \x hex and \u and \U
+// Unicode escapes, \N{U+XXXX}, the control and meta modifiers \C-, \^ and
+// \M- and the ignored escaped newline and space.
+func unescapeString(s string) (string, error) {
+	rs := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(rs); {
+		if rs[i] != '\\' {
+			b.WriteRune(rs[i])
+			i++
+			continue
+		}
+		r, next, err := readEscape(rs, i)
+		if err != nil {
+			return "", err
+		}
+		if r >= 0 {
+			b.WriteRune(r)
+		}
+		i = next
+	}
+	return b.String(), nil
+}
+
+var namedEscapes = map[rune]rune{
+	'a': 7, 'b': 8, 't': 9, 'n': 10, 'v': 11, 'f': 12, 'r': 13,
+	'e': 27, 's': ' ', 'd': 127,
+}
+
+func isHexRune(r rune) bool {
+	return strings.ContainsRune("0123456789abcdefABCDEF", r)
+}
+
+// readChar reads one possibly escaped character at rs[i] and returns it
+// together with the index after it. The rune is -1 for escapes that stand
+// for nothing.
+func readChar(rs []rune, i int) (rune, int, error) {
+	if i >= len(rs) {
+		return 0, i, escapeError{i, "missing character after modifier"}
+	}
+	if rs[i] != '\\' {
+		return rs[i], i + 1, nil
+	}
+	return readEscape(rs, i)
+}
+
+// readEscape reads the escape sequence starting with the backslash at rs[i]
+func readEscape(rs []rune, i int) (rune, int, error) {
+	start := i
+	errorf := func(msg string, args ...interface{}) (rune, int, error) {
+		return 0, i, escapeError{start, fmt.Sprintf(msg, args...)}
+	}
+	i++
+	if i >= len(rs) {
+		return errorf("missing character after '\\'")
+	}
+	c := rs[i]
+	i++
+	if r, ok := namedEscapes[c]; ok {
+		return r, i, nil
+	}
+
+	switch c {
+	case '\n', ' ':
+		return -1, i, nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		j := i - 1
+		for i < len(rs) && i-j < 3 && rs[i] >= '0' && rs[i] <= '7' {
+			i++
+		}
+		r, _ := strconv.ParseUint(string(rs[j:i]), 8, 32)
+		return rune(r), i, nil
+	case 'x', 'u', 'U':
+		j := i
+		for i < len(rs) && isHexRune(rs[i]) && (c == 'x' || i-j < 8) {
+			i++
+		}
+		if c == 'u' && i-j > 4 {
+			i = j + 4
+		}
+		if i == j || (c == 'u' && i-j != 4) || (c == 'U' && i-j != 8) {
+			return errorf("invalid escape sequence '\\%c%s'", c, string(rs[j:i]))
+		}
+		r, err := strconv.ParseUint(string(rs[j:i]), 16, 32)
+		if err != nil || r > 0x10ffff {
+			return errorf("invalid character code '%s'", string(rs[j:i]))
+		}
+		return rune(r), i, nil
+	case 'N':
+		end := i
+		for end < len(rs) && rs[end] != '}' {
+			end++
+		}
+		if i >= len(rs) || rs[i] != '{' || end >= len(rs) {
+			return errorf("invalid character name escape")
+		}
+		name := string(rs[i+1 : end])
+		if !strings.HasPrefix(name, "U+") {
+			return errorf("unsupported character name '%s'", name)
+		}
+		r, err := strconv.ParseUint(name[2:], 16, 32)
+		if err != nil || r > 0x10ffff {
+			return errorf("invalid character name '%s'", name)
+		}
+		return rune(r), end + 1, nil
+	case 'C', '^':
+		if c == 'C' {
+			if i >= len(rs) || rs[i] != '-' {
+				return 'C', i, nil
+			}
+			i++
+		}
+		r, next, err := readChar(rs, i)
+		if err != nil {
+			return r, next, err
+		}
+		if r, ok := control(r); ok {
+			return r, next, nil
+		}
+		return errorf("invalid control character in string")
+	case 'M':
+		if i >= len(rs) || rs[i] != '-' {
+			return 'M', i, nil
+		}
+		r, next, err := readChar(rs, i+1)
+		if err != nil {
+			return r, next, err
+		}
+		if r >= 0x80 {
+			return errorf("invalid meta character in string")
+		}
+		return r | 0x80, next, nil
+	case 'S', 'H', 'A':
+		if i < len(rs) && rs[i] == '-' {
+			return errorf("invalid modifier '\\%c-' in string", c)
+		}
+	}
+	// any other escaped character stands for itself
+	return c, i, nil
+}
+
+// control returns the ASCII control character for r, which exists for ?,
+// letters and @ to _
+func control(r rune) (rune, bool) {
+	switch {
+	case r == '?':
+		return 127, true
+	case r >= 'a' && r <= 'z':
+		return r - 'a' + 1, true
+	case r >= '@' && r <= '_':
+		return r - '@', true
+	}
+	return r, false
+}
+
+// charModifiers are the bits that the modifier escapes \A-, \s-, \H-, \S-
+// and \M- set in a character literal. \C- sets controlBit for characters
+// without an ASCII control character.
+var charModifiers = map[rune]int{
+	'A': 1 << 22, 's': 1 << 23, 'H': 1 << 24, 'S': 1 << 25, 'M': 1 << 27,
+}
+
+const controlBit = 1 << 26
+
+// readCharLiteral returns the character code of the literal s like ?a or
+// ?\C-x. Unlike in strings the modifiers set the high bits of the code, so
+// ?\M-a is 134217825.
+func readCharLiteral(s string) (int, error) {
+	rs := []rune(s)
+	i := 1
+	modifiers := 0
+	isControl := false
+	for i+1 < len(rs) && rs[i] == '\\' {
+		c := rs[i+1]
+		if c == '^' {
+			isControl = true
+			i += 2
+			continue
+		}
+		if i+2 >= len(rs) || rs[i+2] != '-' {
+			break
+		}
+		if c == 'C' {
+			isControl = true
+		} else if bit, ok := charModifiers[c]; ok {
+			modifiers |= bit
+		} else {
+			break
+		}
+		i += 3
+	}
+
+	r, next, err := readChar(rs, i)
+	if err != nil {
+		return 0, err
+	}
+	if r < 0 {
+		// an escaped space or newline stands for itself and is not ignored
+		// like in strings
+		r = rs[next-1]
+	}
+	if next != len(rs) {
+		return 0, escapeError{0, fmt.Sprintf("invalid character literal '%s'", s)}
+	}
+	if isControl {
+		var ok bool
+		if r, ok = control(r); !ok {
+			modifiers |= controlBit
+		}
+	}
+	return int(r) | modifiers, nil
+}
@@ -0,0 +1,99 @@
+package sexp
+
+import (
+	"testing"
+)
+
+func TestUnescapeString(t *testing.T) {
+	testcases := map[string]struct {
+		input        string
+		wanted       string
+		wantedErr    bool
+		wantedOffset int
+	}{
+		"plain": {
+			input:  "abc",
+			wanted: "abc",
+		},
+		"named": {
+			input:  `a\tb\nc\e\s\d`,
+			wanted: "a\tb\nc\x1b \x7f",
+		},
+		"quotes": {
+			input:  `\"x\" \\`,
+			wanted: `"x" \`,
+		},
+		"ignored": {
+			input:  "a\\\nb\\ c",
+			wanted: "abc",
+		},
+		"octal": {
+			input:  `\300\1\0123`,
+			wanted: "À\x01\n3",
+		},
+		"hex": {
+			input:  `\x41\ Bé\U0001F600`,
+			wanted: "ABé😀",
+		},
+		"character name": {
+			input:  `\N{U+41}`,
+			wanted: "A",
+		},
+		"control": {
+			input:  `\C-a\^k\C-?\^@`,
+			wanted: "\x01\x0b\x7f\x00",
+		},
+		"meta": {
+			input:  `\M-a\M-\C-b`,
+			wanted: "á\u0082",
+		},
+		"other": {
+			input:  `\q\C`,
+			wanted: "qC",
+		},
+		"short unicode": {
+			input:        `ab\u12`,
+			wantedErr:    true,
+			wantedOffset: 2,
+		},
+		"empty hex": {
+			input:        `\xg`,
+			wantedErr:    true,
+			wantedOffset: 0,
+		},
+		"named character": {
+			input:        `\N{LATIN SMALL LETTER A}`,
+			wantedErr:    true,
+			wantedOffset: 0,
+		},
+		"super": {
+			input:        `x\S-a`,
+			wantedErr:    true,
+			wantedOffset: 1,
+		},
+		"trailing backslash": {
+			input:        `x\`,
+			wantedErr:    true,
+			wantedOffset: 1,
+		},
+	}
+	for name, tc := range testcases {
+		got, err := unescapeString(tc.input)
+		if tc.wantedErr {
+			escErr, ok := err.(escapeError)
+			if !ok {
+				t.Errorf("%s: expected an escapeError but got '%v'", name, err)
+			} else if escErr.offset != tc.wantedOffset {
+				t.Errorf("%s: Got error at %d but wanted %d: %s", name, escErr.offset, tc.wantedOffset, escErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		if got != tc.wanted {
+			t.Errorf("%s: Got %q but wanted %q", name, got, tc.wanted)
+		}
+	}
+}
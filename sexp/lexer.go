@@ -0,0 +1,452 @@
+package sexp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Token uint
+
+const (
+	OPAREN Token = iota
+	CPAREN
+	DOT
+	IDENT
+	NUMBER
+	STRING
+	OBRACKET
+	CBRACKET
+	HASH
+	QUOTE
+	COMMENT
+)
+
+func (t Token) String() string {
+	switch t {
+	case OPAREN:
+		return "OPAREN"
+	case CPAREN:
+		return "CPAREN"
+	case DOT:
+		return "DOT"
+	case IDENT:
+		return "IDENT"
+	case NUMBER:
+		return "NUMBER"
+	case STRING:
+		return "STRING"
+	case OBRACKET:
+		return "OBRACKET"
+	case CBRACKET:
+		return "CBRACKET"
+	case HASH:
+		return "HASH"
+	case QUOTE:
+		return "QUOTE"
+	case COMMENT:
+		return "COMMENT"
+	}
+	panic(fmt.Sprintf("unexpected token value '%d'", t))
+}
+
+type Position struct {
+	pos uint
+	col uint
+	row uint
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf(":%d:%d (%d)", p.row, p.col, p.pos)
+}
+
+// Offset returns the byte offset from the start of the stream
+func (p Position) Offset() uint {
+	return p.pos
+}
+
+// Row returns the 0-based line number
+func (p Position) Row() uint {
+	return p.row
+}
+
+// Col returns the 0-based column in runes
+func (p Position) Col() uint {
+	return p.col
+}
+
+// Line returns the 1-based line number
+func (p Position) Line() uint {
+	return p.row + 1
+}
+
+// Column returns the 1-based column in runes
+func (p Position) Column() uint {
+	return p.col + 1
+}
+
+// advance returns the position after reading s from p
+func (p Position) advance(s string) Position {
+	for _, r := range s {
+		p.pos += uint(utf8.RuneLen(r))
+		if r == '\n' {
+			p.col = 0
+			p.row++
+		} else {
+			p.col++
+		}
+	}
+	return p
+}
+
+type Lexeme struct {
+	token   Token
+	content string
+	value   string
+
+	start Position
+	end   Position
+}
+
+func (l Lexeme) Token() Token {
+	return l.token
+}
+
+func (l Lexeme) Content() string {
+	return l.content
+}
+
+// Value returns the value of a STRING lexeme with all escape sequences
+// resolved. Content returns the literal as written.
+func (l Lexeme) Value() string {
+	return l.value
+}
+
+func (l Lexeme) Start() Position {
+	return l.start
+}
+
+func (l Lexeme) End() Position {
+	return l.end
+}
+
+type PosReader struct {
+	Position
+	r       rune
+	size    int
+	colsize uint
+	eof     bool
+	err     PosError
+	reader  *bufio.Reader
+}
+
+type Lexer struct {
+	PosReader
+	item     Lexeme
+	startPos Position
+	content  string
+	value    string
+}
+
+func (pr *PosReader) Next() bool {
+	r, size, err := pr.reader.ReadRune()
+
+	if err == io.EOF {
+		// move past the last rune so that the end of file has a position
+		// of its own
+		if !pr.eof {
+			pr.step()
+		}
+		pr.eof = true
+		pr.size = 0
+		pr.colsize = 0
+		return false
+	}
+
+	pr.step()
+	pr.colsize = 1
+	pr.r = r
+	pr.size = size
+
+	if err != nil {
+		pr.err = PosErrorf(pr.Position, "error while reading from stream: %s", err)
+		return false
+	}
+	return true
+}
+
+// step advances the position past the current rune
+func (pr *PosReader) step() {
+	pr.pos += uint(pr.size)
+	if pr.r == '\n' { // XXX: care for CR as well
+		pr.col = 0
+		pr.row += 1
+	} else {
+		pr.col += pr.colsize
+	}
+}
+
+// isSymbolRune reports whether r may appear unescaped in a symbol. Like in
+// the Emacs reader everything but whitespace and the characters with a
+// special meaning to the reader is a symbol constituent.
+func isSymbolRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("()[]\"';`,", r)
+}
+
+// isSymbolStart reports whether r may start a symbol. # starts other read
+// syntaxes and ? a character literal when they are the first character.
+func isSymbolStart(r rune) bool {
+	return isSymbolRune(r) && r != '#' && r != '?'
+}
+
+var (
+	integerRegexp = regexp.MustCompile(`^[-+]?[0-9]+\.?$`)
+	floatRegexp   = regexp.MustCompile(`^[-+]?([0-9]*\.[0-9]+|[0-9]+(\.[0-9]*)?e[-+]?([0-9]+|INF|NaN)|[0-9]*\.[0-9]+e[-+]?([0-9]+|INF|NaN))$`)
+)
+
+// isNumber reports whether the Emacs reader reads the unescaped symbol
+// text s as a number
+func isNumber(s string) bool {
+	return integerRegexp.MatchString(s) || floatRegexp.MatchString(s)
+}
+
+func (l *Lexer) newLexeme(token Token) {
+	l.item = Lexeme{
+		start:   l.startPos,
+		end:     l.Position,
+		content: l.content,
+		value:   l.value,
+		token:   token,
+	}
+}
+
+// return if the rune was matched with the current rune
+// return true in case of an error so that the callee handles the error state.
+func (l *Lexer) acceptRune(r rune, t Token) bool {
+	if l.r == r {
+		l.content = l.content + string(l.r)
+		l.PosReader.Next()
+		if l.err != nil {
+			return true
+		}
+		l.newLexeme(t)
+		return true
+	}
+	return false
+}
+
+// acceptSymbol reads a symbol, a number or a dot. A backslash escapes the
+// following character, which makes it part of the symbol. The content of an
+// IDENT is the symbol name without the escapes, the content of an integer
+// NUMBER is normalised to the digits without a leading '+' and trailing '.'.
+// return true in case of an error so that the callee handles the error state.
+func (l *Lexer) acceptSymbol() bool {
+	escaped := false
+	for !l.eof && isSymbolRune(l.r) {
+		if l.r == '\\' {
+			escaped = true
+			if !l.PosReader.Next() {
+				if l.err == nil {
+					l.err = PosErrorf(l.Position, "end of file after escape character '\\'")
+				}
+				return true
+			}
+		}
+		l.content = l.content + string(l.r)
+		if l.PosReader.Next(); l.err != nil {
+			return true
+		}
+	}
+
+	switch {
+	case escaped:
+		l.newLexeme(IDENT)
+	case l.content == ".":
+		l.newLexeme(DOT)
+	case integerRegexp.MatchString(l.content):
+		l.content = strings.TrimSuffix(strings.TrimPrefix(l.content, "+"), ".")
+		l.newLexeme(NUMBER)
+	case floatRegexp.MatchString(l.content):
+		l.newLexeme(NUMBER)
+	default:
+		l.newLexeme(IDENT)
+	}
+	return true
+}
+
+// acceptString reads a string literal. Its content is the literal as
+// written including the quotes and escapes, its value the decoded string.
+// return true in case of an error so that the callee handles the error state.
+func (l *Lexer) acceptString() bool {
+	if l.r != '"' {
+		return false
+	}
+	escaped := false
+	for {
+		l.content = l.content + string(l.r)
+		if !l.PosReader.Next() {
+			if l.err == nil {
+				l.err = PosErrorf(l.startPos, "end of file in string")
+			}
+			return true
+		}
+		if l.r == '"' && !escaped {
+			break
+		}
+		escaped = l.r == '\\' && !escaped
+	}
+
+	literal := l.content[1:]
+	value, err := unescapeString(literal)
+	if err != nil {
+		escErr := err.(escapeError)
+		// the offset counts runes after the opening quote
+		pos := l.startPos.advance("\"" + string([]rune(literal)[:escErr.offset]))
+		l.err = PosErrorf(pos, "%s", escErr.msg)
+		return true
+	}
+	l.value = value
+	return l.acceptRune('"', STRING)
+}
+
+// acceptChar reads a character literal like ?a or ?\C-x. It is a NUMBER
+// whose content is the character code.
+// return true in case of an error so that the callee handles the error state.
+func (l *Lexer) acceptChar() bool {
+	if l.r != '?' {
+		return false
+	}
+	l.content = "?"
+	for l.PosReader.Next() {
+		// the first character and escaped ones may be delimiters
+		if l.r == '\\' {
+			l.content = l.content + string(l.r)
+			if !l.PosReader.Next() {
+				break
+			}
+		} else if l.content != "?" && !isSymbolRune(l.r) {
+			break
+		}
+		l.content = l.content + string(l.r)
+	}
+	if l.err != nil {
+		return true
+	}
+	if l.content == "?" {
+		l.err = PosErrorf(l.Position, "end of file after '?'")
+		return true
+	}
+
+	code, err := readCharLiteral(l.content)
+	if err != nil {
+		escErr := err.(escapeError)
+		pos := l.startPos.advance(string([]rune(l.content)[:escErr.offset]))
+		l.err = PosErrorf(pos, "%s", escErr.msg)
+		return true
+	}
+	l.content = strconv.Itoa(code)
+	l.newLexeme(NUMBER)
+	return true
+}
+
+// acceptComment reads a comment from ';' up to the end of the line
+// return true in case of an error so that the callee handles the error state.
+func (l *Lexer) acceptComment() bool {
+	if l.r != ';' {
+		return false
+	}
+	for !l.eof && l.r != '\n' {
+		l.content = l.content + string(l.r)
+		if l.PosReader.Next(); l.err != nil {
+			return true
+		}
+	}
+	l.newLexeme(COMMENT)
+	return true
+}
+
+func (l *Lexer) Next() bool {
+	// var content string
+	l.content = ""
+	l.value = ""
+	if l.PosReader.eof {
+		return false
+	}
+
+	// skip leading spaces
+	for unicode.IsSpace(l.r) && l.PosReader.Next() {
+	}
+	if l.err != nil || l.eof {
+		return false
+	}
+
+	l.startPos = l.Position
+	if l.acceptRune('(', OPAREN) {
+		return l.err == nil
+	}
+	if l.acceptRune(')', CPAREN) {
+		return l.err == nil
+	}
+	if l.acceptRune('[', OBRACKET) {
+		return l.err == nil
+	}
+	if l.acceptRune(']', CBRACKET) {
+		return l.err == nil
+	}
+	if l.acceptRune('#', HASH) {
+		return l.err == nil
+	}
+	if l.acceptRune('\'', QUOTE) {
+		return l.err == nil
+	}
+	if l.acceptString() {
+		return l.err == nil
+	}
+	if l.acceptComment() {
+		return l.err == nil
+	}
+	if l.acceptChar() {
+		return l.err == nil
+	}
+	if isSymbolStart(l.r) && l.acceptSymbol() {
+		return l.err == nil
+	}
+	l.err = Unexpected(l.Position, "", fmt.Sprintf("character '%c'", l.r))
+	return false
+}
+
+func (l *Lexer) Scan() Lexeme {
+	return l.item
+}
+
+// Err returns the error that stopped the lexer or nil
+func (l *Lexer) Err() PosError {
+	return l.err
+}
+
+func NewPosReader(r io.Reader) PosReader {
+	pr := PosReader{
+		Position: Position{
+			row: 0,
+			col: 0,
+			pos: 0,
+		},
+		eof:    false,
+		reader: bufio.NewReader(r),
+	}
+	return pr
+}
+
+func NewLexer(r io.Reader) *Lexer {
+	l := Lexer{
+		PosReader: NewPosReader(r),
+	}
+	// l.PosReader.Next()
+	l.r = ' '
+	return &l
+}
@@ -1,10 +1,8 @@
-package main
+package sexp
 
 import (
 	"bufio"
-	"flag"
 	"fmt"
-	"os"
 	"strings"
 	"testing"
 )
@@ -408,6 +406,228 @@ func TestLexer(t *testing.T) {
 	}
 }
 
+func TestLexerSymbols(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		wanted []Lexeme
+	}{
+		"predicate": {
+			input:  "org-babel-execute-src-block-maybe?",
+			wanted: []Lexeme{{token: IDENT, content: "org-babel-execute-src-block-maybe?"}},
+		},
+		"special characters": {
+			input: "cua__prefix <f1> => != 100% $x a#b foo.bar",
+			wanted: []Lexeme{
+				{token: IDENT, content: "cua__prefix"},
+				{token: IDENT, content: "<f1>"},
+				{token: IDENT, content: "=>"},
+				{token: IDENT, content: "!="},
+				{token: IDENT, content: "100%"},
+				{token: IDENT, content: "$x"},
+				{token: IDENT, content: "a#b"},
+				{token: IDENT, content: "foo.bar"},
+			},
+		},
+		"escapes": {
+			input: `foo\ bar \(x\) \12 \. a\\b`,
+			wanted: []Lexeme{
+				{token: IDENT, content: "foo bar"},
+				{token: IDENT, content: "(x)"},
+				{token: IDENT, content: "12"},
+				{token: IDENT, content: "."},
+				{token: IDENT, content: `a\b`},
+			},
+		},
+		"numbers": {
+			input: "8 +8 8. -3 1.5 1e3 1+ -",
+			wanted: []Lexeme{
+				{token: NUMBER, content: "8"},
+				{token: NUMBER, content: "8"},
+				{token: NUMBER, content: "8"},
+				{token: NUMBER, content: "-3"},
+				{token: NUMBER, content: "1.5"},
+				{token: NUMBER, content: "1e3"},
+				{token: IDENT, content: "1+"},
+				{token: IDENT, content: "-"},
+			},
+		},
+		"characters": {
+			input: `?a ?\C-x ?\^? ?\M-a ?\C-% ?\( ?\s ?\  ?\x41 (?b)`,
+			wanted: []Lexeme{
+				{token: NUMBER, content: "97"},
+				{token: NUMBER, content: "24"},
+				{token: NUMBER, content: "127"},
+				{token: NUMBER, content: "134217825"},
+				{token: NUMBER, content: "67108901"},
+				{token: NUMBER, content: "40"},
+				{token: NUMBER, content: "32"},
+				{token: NUMBER, content: "32"},
+				{token: NUMBER, content: "65"},
+				{token: OPAREN, content: "("},
+				{token: NUMBER, content: "98"},
+				{token: CPAREN, content: ")"},
+			},
+		},
+		"strings": {
+			input: `"abc" "a \"quoted\" \\ string" "\C-a"`,
+			wanted: []Lexeme{
+				{token: STRING, content: `"abc"`},
+				{token: STRING, content: `"a \"quoted\" \\ string"`},
+				{token: STRING, content: `"\C-a"`},
+			},
+		},
+		"vectors": {
+			input: "[97 return] #[257 \"\\300\" [x] 3]",
+			wanted: []Lexeme{
+				{token: OBRACKET, content: "["},
+				{token: NUMBER, content: "97"},
+				{token: IDENT, content: "return"},
+				{token: CBRACKET, content: "]"},
+				{token: HASH, content: "#"},
+				{token: OBRACKET, content: "["},
+				{token: NUMBER, content: "257"},
+				{token: STRING, content: `"\300"`},
+				{token: OBRACKET, content: "["},
+				{token: IDENT, content: "x"},
+				{token: CBRACKET, content: "]"},
+				{token: NUMBER, content: "3"},
+				{token: CBRACKET, content: "]"},
+			},
+		},
+		"comments": {
+			input: ";; -*- coding: utf-8 -*-\n(a ; trailing\n b)",
+			wanted: []Lexeme{
+				{token: COMMENT, content: ";; -*- coding: utf-8 -*-"},
+				{token: OPAREN, content: "("},
+				{token: IDENT, content: "a"},
+				{token: COMMENT, content: "; trailing"},
+				{token: IDENT, content: "b"},
+				{token: CPAREN, content: ")"},
+			},
+		},
+		"quote": {
+			input: "'(a 'b)",
+			wanted: []Lexeme{
+				{token: QUOTE, content: "'"},
+				{token: OPAREN, content: "("},
+				{token: IDENT, content: "a"},
+				{token: QUOTE, content: "'"},
+				{token: IDENT, content: "b"},
+				{token: CPAREN, content: ")"},
+			},
+		},
+		"delimiters": {
+			input: "(a.b . c)",
+			wanted: []Lexeme{
+				{token: OPAREN, content: "("},
+				{token: IDENT, content: "a.b"},
+				{token: DOT, content: "."},
+				{token: IDENT, content: "c"},
+				{token: CPAREN, content: ")"},
+			},
+		},
+	}
+	for name, tc := range testcases {
+		var got []Lexeme
+		lexer := NewLexer(strings.NewReader(tc.input))
+		for lexer.Next() {
+			got = append(got, lexer.Scan())
+		}
+		if lexer.Err() != nil {
+			t.Errorf("Lexer TC '%s': unexpected error: %s", name, lexer.Err())
+		}
+		if err := compareLexItems(got, tc.wanted); err != nil {
+			t.Errorf("Lexer TC '%s' failed: %s", name, err)
+		}
+	}
+}
+
+func TestLexerStringValue(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		wanted string
+	}{
+		"plain": {
+			input:  `"abc"`,
+			wanted: "abc",
+		},
+		"escapes": {
+			input:  `"a\"b\" \\ \t\C-a\x41\ "`,
+			wanted: "a\"b\" \\ \t\x01A",
+		},
+		"multi line": {
+			input:  "\"a\\\nb\nc\"",
+			wanted: "ab\nc",
+		},
+	}
+	for name, tc := range testcases {
+		lexer := NewLexer(strings.NewReader(tc.input))
+		if !lexer.Next() {
+			t.Errorf("%s: unexpected error: %s", name, lexer.Err())
+			continue
+		}
+		item := lexer.Scan()
+		if item.Token() != STRING || item.Content() != tc.input {
+			t.Errorf("%s: Got %s '%s' but wanted the STRING '%s'", name, item.Token(), item.Content(), tc.input)
+		}
+		if item.Value() != tc.wanted {
+			t.Errorf("%s: Got value %q but wanted %q", name, item.Value(), tc.wanted)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	testcases := map[string]struct {
+		input     string
+		wantedErr string
+	}{
+		"comma": {
+			input:     "foo\n ,bar",
+			wantedErr: "2:2: unexpected character ','",
+		},
+		"unterminated string": {
+			input:     `(foo "bar)`,
+			wantedErr: "1:6: end of file in string",
+		},
+		"character literal": {
+			input:     "(?ab)",
+			wantedErr: "1:2: invalid character literal '?ab'",
+		},
+		"end of file after character literal": {
+			input:     "(?",
+			wantedErr: "1:3: end of file after '?'",
+		},
+		"invalid character escape": {
+			input:     `?\C-`,
+			wantedErr: "1:5: missing character after modifier",
+		},
+		"invalid string escape": {
+			input:     "(\"a\nb\\u12\")",
+			wantedErr: "2:2: invalid escape sequence '\\u12'",
+		},
+		"escape at end of file": {
+			input:     `foo\`,
+			wantedErr: `1:5: end of file after escape character '\'`,
+		},
+	}
+	for name, tc := range testcases {
+		lexer := NewLexer(strings.NewReader(tc.input))
+		for lexer.Next() {
+		}
+		err := lexer.Err()
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if _, ok := err.(LexPosError); !ok {
+			t.Errorf("%s: expected a LexPosError but got %T", name, err)
+		}
+		if err.Error() != tc.wantedErr {
+			t.Errorf("%s: Got error '%s' but wanted '%s'", name, err, tc.wantedErr)
+		}
+	}
+}
+
 func TestToken(t *testing.T) {
 	testcases := map[string]struct {
 		input  Token
@@ -433,6 +653,30 @@ func TestToken(t *testing.T) {
 			input:  NUMBER,
 			wanted: "NUMBER",
 		},
+		"string": {
+			input:  STRING,
+			wanted: "STRING",
+		},
+		"brackets": {
+			input:  OBRACKET,
+			wanted: "OBRACKET",
+		},
+		"closing bracket": {
+			input:  CBRACKET,
+			wanted: "CBRACKET",
+		},
+		"hash": {
+			input:  HASH,
+			wanted: "HASH",
+		},
+		"quote": {
+			input:  QUOTE,
+			wanted: "QUOTE",
+		},
+		"comment": {
+			input:  COMMENT,
+			wanted: "COMMENT",
+		},
 	}
 	for name, tc := range testcases {
 		got := tc.input.String()
@@ -463,113 +707,3 @@ func TestPosition(t *testing.T) {
 		}
 	}
 }
-
-func TestParserReadFunc(t *testing.T) {
-	testcases := map[string]struct {
-		input  string
-		wanted ModeFunc
-	}{
-		"basic": {
-			input: "(my-mode . my-function)",
-			wanted: ModeFunc{
-				Function: "my-function",
-				Mode:     "my-mode",
-			},
-		},
-	}
-	for name, tc := range testcases {
-		reader := strings.NewReader(tc.input)
-		parser := new(Parser)
-		parser.init(reader)
-
-		got, err := parser.readModeFunction()
-		if err != nil {
-			t.Errorf("%s: unexpected error: '%s'", name, err)
-			continue
-		}
-		if got != tc.wanted {
-			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
-		}
-	}
-}
-
-func TestOutMode(t *testing.T) {
-	testcases := map[string]struct {
-		input        string
-		wanted       OutMode
-		wantedString string
-	}{
-		"all": {
-			input:        "all",
-			wanted:       ALL,
-			wantedString: "ALL",
-		},
-	}
-	for name, tc := range testcases {
-		om, err := OutModeParse(tc.input)
-		if err != nil {
-			t.Errorf("%s: readArgs returned unexpected error: %s", name, err)
-			continue
-		}
-		if tc.wanted != om {
-			t.Errorf("%s: parsing did not yield correct result. Wanted: '%s' Got: '%s'",
-				name, tc.wanted, om)
-		}
-		toString := tc.wanted.String()
-		if toString != tc.wantedString {
-			t.Errorf("%s: String() did not yield correct result. Wanted: '%s' Got: '%s'",
-				name, tc.wantedString, toString)
-		}
-	}
-}
-
-func TestOpts(t *testing.T) {
-	path := "/home/.emacs.keyfreq"
-	testcases := map[string]struct {
-		input  []string
-		wanted Opts
-	}{
-		"basic": {
-			input: []string{"keyfreq", "-i", path},
-			wanted: Opts{
-				inputFilename: path,
-				mode:          ALL,
-			},
-		},
-		"modes": {
-			input: []string{"keyfreq", "-i", path, "-mode", "modes"},
-			wanted: Opts{
-				inputFilename: path,
-				mode:          MODES,
-			},
-		},
-		"functions": {
-			input: []string{"keyfreq", "-i", path, "-mode", "functions"},
-			wanted: Opts{
-				inputFilename: path,
-				mode:          FUNCTIONS,
-			},
-		},
-	}
-	oldArgs := os.Args
-	oldCmd := flag.CommandLine
-	defer func() {
-		os.Args = oldArgs
-		flag.CommandLine = oldCmd
-	}()
-	for name, tc := range testcases {
-		os.Args = tc.input
-		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
-
-		var o Opts
-		err := o.readArgs()
-		if err != nil {
-			t.Errorf("%s: readArgs returned unexpected error: %s", name, err)
-			continue
-		}
-		if tc.wanted != o {
-			t.Errorf("%s: Parsing Arguments failed. Wanted '%s'. Got '%s'",
-				name, tc.wanted, o)
-		}
-	}
-}
@@ -0,0 +1,129 @@
+package sexp
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultWidth is the line width used by Fprint
+const DefaultWidth = 80
+
+// Printer pretty-prints values. Lists and vectors that do not fit in Width
+// columns are broken into one element per line, aligned after the opening
+// parenthesis like pp does:
+//
+//	(((org-mode . next-line) . 3)
+//	 ((org-mode . org-cycle) . 2))
+type Printer struct {
+	Width int
+}
+
+// Fprint writes the pretty-printed v to w followed by a newline
+func (p *Printer) Fprint(w io.Writer, v Value) error {
+	_, err := io.WriteString(w, p.Sprint(v)+"\n")
+	return err
+}
+
+// Sprint returns the pretty-printed v without a trailing newline
+func (p *Printer) Sprint(v Value) string {
+	var b strings.Builder
+	p.print(&b, v, 0)
+	return b.String()
+}
+
+// print writes v starting at column col
+func (p *Printer) print(w *strings.Builder, v Value, col int) {
+	if flatWidth(v, p.Width-col) <= p.Width-col {
+		w.WriteString(v.String())
+		return
+	}
+
+	var open, close string
+	var items []Value
+	var tail Value
+	switch v := v.(type) {
+	case List:
+		if prefix, quoted, ok := quotedForm(v); ok {
+			w.WriteString(prefix)
+			p.print(w, quoted, col+len(prefix))
+			return
+		}
+		open, close = "(", ")"
+		items, tail = v.flatten()
+	case Vector:
+		open, close, items = "[", "]", v.Items
+	case ByteCode:
+		open, close, items = "#[", "]", v.Items
+	case Record:
+		open, close, items = "#s(", ")", v.Items
+	default:
+		w.WriteString(v.String())
+		return
+	}
+
+	w.WriteString(open)
+	indent := col + len(open)
+	for i, item := range items {
+		if i > 0 {
+			newline(w, indent)
+		}
+		p.print(w, item, indent)
+	}
+	if tail != nil {
+		newline(w, indent)
+		w.WriteString(". ")
+		p.print(w, tail, indent+2)
+	}
+	w.WriteString(close)
+}
+
+// flatWidth returns the width of v printed on a single line. It stops
+// counting once the width exceeds limit, so that the values nested in a
+// broken list are not printed again for every level.
+func flatWidth(v Value, limit int) int {
+	switch v := v.(type) {
+	case List:
+		if prefix, quoted, ok := quotedForm(v); ok {
+			return len(prefix) + flatWidth(quoted, limit-len(prefix))
+		}
+		items, tail := v.flatten()
+		return itemsWidth(len("()"), items, tail, limit)
+	case Vector:
+		return itemsWidth(len("[]"), v.Items, nil, limit)
+	case ByteCode:
+		return itemsWidth(len("#[]"), v.Items, nil, limit)
+	case Record:
+		return itemsWidth(len("#s()"), v.Items, nil, limit)
+	}
+	return utf8.RuneCountInString(v.String())
+}
+
+// itemsWidth adds the width of the items separated by spaces and of the
+// dotted tail to width up to limit
+func itemsWidth(width int, items []Value, tail Value, limit int) int {
+	for i, item := range items {
+		if width > limit {
+			return width
+		}
+		if i > 0 {
+			width++
+		}
+		width += flatWidth(item, limit-width)
+	}
+	if tail != nil && width <= limit {
+		width += len(" . ")
+		width += flatWidth(tail, limit-width)
+	}
+	return width
+}
+
+func newline(w *strings.Builder, indent int) {
+	w.WriteByte('\n')
+	w.WriteString(strings.Repeat(" ", indent))
+}
+
+// Fprint pretty-prints v to w with the DefaultWidth
+func Fprint(w io.Writer, v Value) error {
+	return (&Printer{Width: DefaultWidth}).Fprint(w, v)
+}
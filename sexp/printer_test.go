@@ -0,0 +1,99 @@
+package sexp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPrinter(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		width  int
+		wanted string
+	}{
+		"fits": {
+			input:  "(a   (b\n c)  [d])",
+			width:  80,
+			wanted: "(a (b c) [d])",
+		},
+		"alist": {
+			input:  "(((org-mode . next-line) . 3) ((org-mode . org-cycle) . 2))",
+			width:  40,
+			wanted: "(((org-mode . next-line) . 3)\n ((org-mode . org-cycle) . 2))",
+		},
+		"nested": {
+			input:  `("first string" ["a vector" element] last)`,
+			width:  20,
+			wanted: "(\"first string\"\n [\"a vector\"\n  element]\n last)",
+		},
+		"dotted": {
+			input:  "(long-symbol . another-long-symbol)",
+			width:  20,
+			wanted: "(long-symbol\n . another-long-symbol)",
+		},
+		"quoted": {
+			input:  "'(alpha beta gamma)",
+			width:  10,
+			wanted: "'(alpha\n  beta\n  gamma)",
+		},
+		"byte-code": {
+			input:  "#[257 \"\\300\" [x] 3]",
+			width:  10,
+			wanted: "#[257\n  \"\\300\"\n  [x]\n  3]",
+		},
+		"deep": {
+			input:  strings.Repeat("(a ", 1000) + strings.Repeat(")", 1000),
+			width:  4000,
+			wanted: strings.Repeat("(a ", 999) + "(a" + strings.Repeat(")", 1000),
+		},
+		"atom": {
+			input:  "a-very-long-symbol",
+			width:  5,
+			wanted: "a-very-long-symbol",
+		},
+	}
+	for name, tc := range testcases {
+		v, err := NewReader(strings.NewReader(tc.input)).Read()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		flat := utf8.RuneCountInString(v.String())
+		if got := flatWidth(v, flat); got != flat {
+			t.Errorf("%s: Got flat width %d but wanted %d", name, got, flat)
+		}
+		if got := flatWidth(v, flat-2); got <= flat-2 {
+			t.Errorf("%s: Got flat width %d within the limit %d", name, got, flat-2)
+		}
+		printer := Printer{Width: tc.width}
+		if got := printer.Sprint(v); got != tc.wanted {
+			t.Errorf("%s: Got\n%s\nbut wanted\n%s", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestFprint(t *testing.T) {
+	input := `(("file.txt" . 3) (#s(x) 'y) [1.5 -2])`
+	v, err := NewReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var b bytes.Buffer
+	if err := Fprint(&b, v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := b.String(); got != input+"\n" {
+		t.Errorf("Got %q but wanted %q", got, input+"\n")
+	}
+
+	// printing and reading again gives the same value
+	reread, err := NewReader(&b).Read()
+	if err != nil {
+		t.Fatalf("can't read printed value: %s", err)
+	}
+	if reread.String() != v.String() {
+		t.Errorf("Got '%s' after reading back but wanted '%s'", reread, v)
+	}
+}
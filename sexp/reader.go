@@ -0,0 +1,318 @@
+package sexp
+
+import (
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Reader reads Emacs Lisp data. Read returns one complete datum. Token,
+// Peek and More step through lists lexeme by lexeme, which allows to
+// stream the elements of a large list with Read.
+type Reader struct {
+	lexer  *Lexer
+	peeked bool
+	item   Lexeme
+	err    error
+	// open holds the closing tokens of the lists and vectors entered by
+	// Token
+	open []Token
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		lexer: NewLexer(r),
+	}
+}
+
+// Position returns the position of the lexer
+func (r *Reader) Position() Position {
+	return r.lexer.Position
+}
+
+// Depth returns the number of lists and vectors that are open. It can be
+// used to skip the rest of a datum with Token after an error of Read.
+func (r *Reader) Depth() int {
+	return len(r.open)
+}
+
+// Peek returns the next lexeme without consuming it. Comments are skipped.
+// At the end of the input it returns io.EOF outside of any list and a
+// PosError inside.
+func (r *Reader) Peek() (Lexeme, error) {
+	if r.peeked {
+		return r.item, r.err
+	}
+	r.peeked = true
+	r.item, r.err = Lexeme{}, nil
+	for {
+		if !r.lexer.Next() {
+			switch {
+			case r.lexer.Err() != nil:
+				r.err = r.lexer.Err()
+			case len(r.open) > 0:
+				r.err = Unexpected(r.lexer.Position, r.closing(), "end of file")
+			default:
+				r.err = io.EOF
+			}
+			return r.item, r.err
+		}
+		if r.item = r.lexer.Scan(); r.item.token != COMMENT {
+			return r.item, nil
+		}
+	}
+}
+
+// Token consumes the next lexeme. Opening and closing parentheses and
+// brackets must be balanced.
+func (r *Reader) Token() (Lexeme, error) {
+	item, err := r.Peek()
+	if err != nil {
+		return item, err
+	}
+	switch item.token {
+	case OPAREN:
+		r.open = append(r.open, CPAREN)
+	case OBRACKET:
+		r.open = append(r.open, CBRACKET)
+	case CPAREN, CBRACKET:
+		if len(r.open) == 0 || r.open[len(r.open)-1] != item.token {
+			return item, Unexpected(item.start, r.closing(), quote(item.content))
+		}
+		r.open = r.open[:len(r.open)-1]
+	}
+	r.peeked = false
+	return item, nil
+}
+
+// More reports whether there is another element before the closing
+// parenthesis or bracket of the current list. It returns false on errors,
+// which are returned by the next call to Token or Read.
+func (r *Reader) More() bool {
+	item, err := r.Peek()
+	return err == nil && item.token != CPAREN && item.token != CBRACKET
+}
+
+// Read reads the next datum. It returns io.EOF when the input ends before
+// the datum starts outside of any list.
+func (r *Reader) Read() (Value, error) {
+	item, err := r.Token()
+	if err != nil {
+		return nil, err
+	}
+	s := span{start: item.start, end: item.end}
+	switch item.token {
+	case IDENT:
+		return Symbol{span: s, Name: item.content}, nil
+	case NUMBER:
+		return Number{span: s, Text: item.content}, nil
+	case STRING:
+		return String{span: s, Value: item.value, Literal: item.content}, nil
+	case QUOTE:
+		return r.readQuoted(s, "quote")
+	case OPAREN:
+		return r.readList(item.start)
+	case OBRACKET:
+		items, end, err := r.readItems()
+		return Vector{span: span{start: item.start, end: end}, Items: items}, err
+	case HASH:
+		return r.readHash(item)
+	}
+	return nil, Unexpected(item.start, "", quote(item.content))
+}
+
+// closing returns the quoted delimiter that closes the innermost open list
+// or vector or an empty string outside of any list
+func (r *Reader) closing() string {
+	if len(r.open) == 0 {
+		return ""
+	}
+	if r.open[len(r.open)-1] == CBRACKET {
+		return quote("]")
+	}
+	return quote(")")
+}
+
+// quote returns the lexeme content s as it appears in error messages
+func quote(s string) string {
+	return "'" + s + "'"
+}
+
+// readQuoted reads the datum after 'x or #'x as (name x). The symbol spans
+// the quote characters.
+func (r *Reader) readQuoted(quote span, name string) (Value, error) {
+	v, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return List{
+		span:  span{start: quote.start, end: v.End()},
+		Items: []Value{Symbol{span: quote, Name: name}, v},
+	}, nil
+}
+
+// read reads a datum that must be there
+func (r *Reader) read() (Value, error) {
+	v, err := r.Read()
+	if err == io.EOF {
+		return nil, Unexpected(r.lexer.Position, "", "end of file")
+	}
+	return v, err
+}
+
+// readItems reads the elements of a list or vector up to and including
+// its closing delimiter and returns them with the end position
+func (r *Reader) readItems() ([]Value, Position, error) {
+	var items []Value
+	for r.More() {
+		v, err := r.Read()
+		if err != nil {
+			return nil, Position{}, err
+		}
+		items = append(items, v)
+	}
+	// Token makes sure that the delimiter matches
+	end, err := r.Token()
+	if err != nil {
+		return nil, Position{}, err
+	}
+	return items, end.end, nil
+}
+
+// readList reads the rest of a list after its opening parenthesis. A dot
+// before the last element makes it the Tail.
+func (r *Reader) readList(start Position) (Value, error) {
+	l := List{span: span{start: start}}
+	for r.More() {
+		item, _ := r.Peek()
+		if item.token == DOT {
+			if len(l.Items) == 0 {
+				return nil, PosErrorf(item.start, "unexpected '.' at the start of a list")
+			}
+			r.Token()
+			tail, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			l.Tail = tail
+			if r.More() {
+				item, _ := r.Peek()
+				return nil, Unexpected(item.start, "')' after the dotted tail", quote(item.content))
+			}
+			break
+		}
+		v, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		l.Items = append(l.Items, v)
+	}
+	end, err := r.Token()
+	if err != nil {
+		return nil, err
+	}
+	l.close = end.start
+	l.end = end.end
+	return l, nil
+}
+
+// readHash reads the # dispatch syntaxes #[...] for byte-code, #'x for
+// (function x), #s(...) for records, #("text" ...) for propertized strings,
+// ## for the symbol with the empty name and the integers #b101, #o17, #x1F
+// and #24r1k
+func (r *Reader) readHash(hash Lexeme) (Value, error) {
+	item, err := r.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if item.start != hash.end {
+		return nil, PosErrorf(hash.start, "unsupported read syntax '#'")
+	}
+	switch {
+	case item.token == OBRACKET:
+		r.Token()
+		items, end, err := r.readItems()
+		return ByteCode{span: span{start: hash.start, end: end}, Items: items}, err
+	case item.token == QUOTE:
+		r.Token()
+		return r.readQuoted(span{start: hash.start, end: item.end}, "function")
+	case item.token == HASH:
+		r.Token()
+		return Symbol{span: span{start: hash.start, end: item.end}}, nil
+	case item.token == OPAREN:
+		r.Token()
+		items, end, err := r.readItems()
+		if err != nil {
+			return nil, err
+		}
+		str, ok := String{}, false
+		if len(items) > 0 {
+			str, ok = items[0].(String)
+		}
+		if !ok {
+			return nil, PosErrorf(hash.start, "expected a string after '#('")
+		}
+		str.span = span{start: hash.start, end: end}
+		str.Properties = items[1:]
+		return str, nil
+	case item.token == IDENT && item.content == "s":
+		r.Token()
+		open, err := r.Token()
+		if err != nil {
+			return nil, err
+		}
+		if open.token != OPAREN || open.start != item.end {
+			return nil, PosErrorf(hash.start, "unsupported read syntax '#s'")
+		}
+		items, end, err := r.readItems()
+		return Record{span: span{start: hash.start, end: end}, Items: items}, err
+	}
+	if base, digits, ok := radix(item); ok {
+		r.Token()
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, PosErrorf(hash.start, "invalid integer '#%s' in base %d", item.content, base)
+		}
+		return Number{span: span{start: hash.start, end: item.end}, Text: n.String()}, nil
+	}
+	return nil, PosErrorf(hash.start, "unsupported read syntax '#%s'", item.content)
+}
+
+var radixPrefixes = map[byte]int{'b': 2, 'B': 2, 'o': 8, 'O': 8, 'x': 16, 'X': 16}
+
+// radix returns the base and the digits of the integer syntaxes #b, #o, #x
+// and #<base>r after the #
+func radix(item Lexeme) (int, string, bool) {
+	if item.token != IDENT || item.content == "" {
+		return 0, "", false
+	}
+	if base, ok := radixPrefixes[item.content[0]]; ok {
+		return base, item.content[1:], true
+	}
+	i := strings.IndexAny(item.content, "rR")
+	if i <= 0 {
+		return 0, "", false
+	}
+	base, err := strconv.Atoi(item.content[:i])
+	if err != nil || base < 2 || base > 36 {
+		return 0, "", false
+	}
+	return base, item.content[i+1:], true
+}
+
+// ReadAll reads all data from r
+func ReadAll(r io.Reader) ([]Value, error) {
+	reader := NewReader(r)
+	var values []Value
+	for {
+		v, err := reader.Read()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+}
@@ -0,0 +1,246 @@
+package sexp
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	testcases := map[string]struct {
+		input  string
+		wanted []string
+	}{
+		"atoms": {
+			input:  `foo 12 +3. 1.5 "a\tb" foo\ bar`,
+			wanted: []string{"foo", "12", "3", "1.5", `"a\tb"`, `foo\ bar`},
+		},
+		"lists": {
+			input:  "(a (b c) ()) (a . b) (a b . c) (a . (b c)) (a . nil)",
+			wanted: []string{"(a (b c) ())", "(a . b)", "(a b . c)", "(a b c)", "(a)"},
+		},
+		"vectors": {
+			input:  `[1 [2] "x"] #[257 "\300" [x] 3]`,
+			wanted: []string{`[1 [2] "x"]`, `#[257 "\300" [x] 3]`},
+		},
+		"propertized string": {
+			input:  `#("find-file" 0 4 (face bold) 4 9 nil) #("x")`,
+			wanted: []string{`#("find-file" 0 4 (face bold) 4 9 nil)`, `"x"`},
+		},
+		"radix integers": {
+			input:  "#x1F #o17 #b101 #X-10 #24r1k #x10000000000000000",
+			wanted: []string{"31", "15", "5", "-16", "44", "18446744073709551616"},
+		},
+		"record": {
+			input:  "#s(hash-table size 1 data (a 1))",
+			wanted: []string{"#s(hash-table size 1 data (a 1))"},
+		},
+		"empty symbol": {
+			input:  "## (a ##)",
+			wanted: []string{"##", "(a ##)"},
+		},
+		"quote": {
+			input:  "'a '(a 'b) #'car (quote x)",
+			wanted: []string{"'a", "'(a 'b)", "#'car", "'x"},
+		},
+		"comments": {
+			input:  ";; -*- coding: utf-8 -*-\n(a ; first\n b) ; done",
+			wanted: []string{"(a b)"},
+		},
+		"empty": {
+			input:  " ;; nothing\n",
+			wanted: nil,
+		},
+	}
+	for name, tc := range testcases {
+		values, err := ReadAll(strings.NewReader(tc.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		var got []string
+		for _, v := range values {
+			got = append(got, v.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.wanted, "\n") {
+			t.Errorf("%s: Got %q but wanted %q", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestReadTypes(t *testing.T) {
+	values, err := ReadAll(strings.NewReader(`((mode . "a\"b") [x] #[y] #s(z) #("t" 0 1 (face bold)))`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	l, ok := values[0].(List)
+	if !ok || len(l.Items) != 5 || !l.IsProper() {
+		t.Fatalf("Got %#v but wanted a proper list of 5 items", values[0])
+	}
+	pair, ok := l.Items[0].(List)
+	if !ok || len(pair.Items) != 1 || pair.IsProper() {
+		t.Fatalf("Got %#v but wanted a cons cell", l.Items[0])
+	}
+	if mode, ok := pair.Car().(Symbol); !ok || mode.Name != "mode" {
+		t.Errorf("Got car %#v but wanted the symbol mode", pair.Car())
+	}
+	if s, ok := pair.Cdr().(String); !ok || s.Value != `a"b` || s.Literal != `"a\"b"` {
+		t.Errorf("Got cdr %#v but wanted the string a\"b", pair.Cdr())
+	}
+	if _, ok := l.Items[1].(Vector); !ok {
+		t.Errorf("Got %#v but wanted a Vector", l.Items[1])
+	}
+	if _, ok := l.Items[2].(ByteCode); !ok {
+		t.Errorf("Got %#v but wanted a ByteCode", l.Items[2])
+	}
+	if _, ok := l.Items[3].(Record); !ok {
+		t.Errorf("Got %#v but wanted a Record", l.Items[3])
+	}
+	if s, ok := l.Items[4].(String); !ok || s.Value != "t" || len(s.Properties) != 3 {
+		t.Errorf("Got %#v but wanted the string t with properties", l.Items[4])
+	}
+}
+
+func TestReadPositions(t *testing.T) {
+	reader := NewReader(strings.NewReader("(a\n (b . 12))\n"))
+	v, err := reader.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	l := v.(List)
+	pair := l.Items[1].(List)
+	testcases := map[string]struct {
+		got    Position
+		wanted Position
+	}{
+		"list start": {
+			got:    l.Start(),
+			wanted: Position{pos: 0, row: 0, col: 0},
+		},
+		"list end": {
+			got:    l.End(),
+			wanted: Position{pos: 13, row: 1, col: 10},
+		},
+		"list close": {
+			got:    l.Close(),
+			wanted: Position{pos: 12, row: 1, col: 9},
+		},
+		"pair start": {
+			got:    pair.Start(),
+			wanted: Position{pos: 4, row: 1, col: 1},
+		},
+		"tail start": {
+			got:    pair.Tail.Start(),
+			wanted: Position{pos: 9, row: 1, col: 6},
+		},
+		"tail end": {
+			got:    pair.Tail.End(),
+			wanted: Position{pos: 11, row: 1, col: 8},
+		},
+	}
+	for name, tc := range testcases {
+		if tc.got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, tc.got, tc.wanted)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	testcases := map[string]struct {
+		input     string
+		wantedErr string
+	}{
+		"unbalanced": {
+			input:     "(a [b)",
+			wantedErr: "1:6: expected ']' but got ')'",
+		},
+		"stray paren": {
+			input:     "a)",
+			wantedErr: "1:2: unexpected ')'",
+		},
+		"end of file": {
+			input:     "(a (b",
+			wantedErr: "1:6: expected ')' but got end of file",
+		},
+		"end of file after quote": {
+			input:     "'",
+			wantedErr: "1:2: unexpected end of file",
+		},
+		"leading dot": {
+			input:     "( . a)",
+			wantedErr: "1:3: unexpected '.' at the start of a list",
+		},
+		"after dotted tail": {
+			input:     "(a . b c)",
+			wantedErr: "1:8: expected ')' after the dotted tail but got 'c'",
+		},
+		"dot outside list": {
+			input:     "[a . b]",
+			wantedErr: "1:4: unexpected '.'",
+		},
+		"unsupported hash": {
+			input:     "(#@10)",
+			wantedErr: "1:2: unsupported read syntax '#@10'",
+		},
+		"invalid radix integer": {
+			input:     "(#x1G)",
+			wantedErr: "1:2: invalid integer '#x1G' in base 16",
+		},
+		"propertized symbol": {
+			input:     "#(a 0 1 nil)",
+			wantedErr: "1:1: expected a string after '#('",
+		},
+		"detached hash": {
+			input:     "# [a]",
+			wantedErr: "1:1: unsupported read syntax '#'",
+		},
+		"lexer error": {
+			input:     "(a ,b)",
+			wantedErr: "1:4: unexpected character ','",
+		},
+	}
+	for name, tc := range testcases {
+		_, err := ReadAll(strings.NewReader(tc.input))
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		if _, ok := err.(PosError); !ok {
+			t.Errorf("%s: expected a PosError but got %T", name, err)
+		}
+		if err.Error() != tc.wantedErr {
+			t.Errorf("%s: Got error '%s' but wanted '%s'", name, err, tc.wantedErr)
+		}
+	}
+}
+
+func TestReaderStream(t *testing.T) {
+	reader := NewReader(strings.NewReader("; entries\n((a . 1) (b . 2))"))
+	open, err := reader.Token()
+	if err != nil || open.Token() != OPAREN {
+		t.Fatalf("Got %s '%s' (%v) but wanted '('", open.Token(), open.Content(), err)
+	}
+	if reader.Depth() != 1 {
+		t.Errorf("Got depth %d inside the list but wanted 1", reader.Depth())
+	}
+	var got []string
+	for reader.More() {
+		v, err := reader.Read()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got = append(got, v.String())
+	}
+	if strings.Join(got, " ") != "(a . 1) (b . 2)" {
+		t.Errorf("Got entries %q", got)
+	}
+	if item, err := reader.Token(); err != nil || item.Token() != CPAREN {
+		t.Errorf("Got %s '%s' (%v) but wanted ')'", item.Token(), item.Content(), err)
+	}
+	if reader.Depth() != 0 {
+		t.Errorf("Got depth %d after the list but wanted 0", reader.Depth())
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Got '%v' but wanted io.EOF", err)
+	}
+}
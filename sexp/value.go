@@ -0,0 +1,304 @@
+package sexp
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is a datum read by Reader: a Symbol, Number, String, List, Vector,
+// ByteCode or Record. String returns its read syntax on a single line.
+type Value interface {
+	Start() Position
+	End() Position
+	String() string
+}
+
+// span is the source range of a value. It is zero for constructed values.
+type span struct {
+	start Position
+	end   Position
+}
+
+// Start returns the position of the first character of the value
+func (s span) Start() Position {
+	return s.start
+}
+
+// End returns the position after the last character of the value
+func (s span) End() Position {
+	return s.end
+}
+
+type Symbol struct {
+	span
+	Name string
+}
+
+func (s Symbol) String() string {
+	return EscapeSymbol(s.Name)
+}
+
+// Number is an integer or a float. The text of an integer is normalised to
+// its digits without a leading '+' or trailing '.', floats keep the text as
+// written.
+type Number struct {
+	span
+	Text string
+}
+
+func (n Number) String() string {
+	return n.Text
+}
+
+// IsInteger reports whether the number is read as an integer
+func (n Number) IsInteger() bool {
+	return integerRegexp.MatchString(n.Text)
+}
+
+// Int64 returns the value of an integer
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(n.Text, 10, 64)
+}
+
+// Float64 returns the value of an integer or float including the infinity
+// and NaN floats like 1.0e+INF
+func (n Number) Float64() (float64, error) {
+	if i := strings.IndexByte(n.Text, 'e'); i >= 0 {
+		sign := 1
+		if strings.HasPrefix(n.Text, "-") {
+			sign = -1
+		}
+		switch strings.TrimPrefix(n.Text[i+1:], "+") {
+		case "INF":
+			return math.Inf(sign), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+	}
+	return strconv.ParseFloat(n.Text, 64)
+}
+
+type String struct {
+	span
+	// Value is the string with all escape sequences resolved
+	Value string
+	// Literal is the string as written in the source including the quotes.
+	// It is empty for constructed strings. When set it is printed instead
+	// of Value so that strings read from a file are written back unchanged.
+	Literal string
+	// Properties are the text properties of a propertized string
+	// #("text" 0 4 (face bold)) as read: start, end and property list
+	Properties []Value
+}
+
+func (s String) String() string {
+	text := s.Literal
+	if text == "" {
+		text = quoteString(s.Value)
+	}
+	if len(s.Properties) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString("#(")
+	b.WriteString(text)
+	b.WriteByte(' ')
+	writeItems(&b, s.Properties)
+	b.WriteByte(')')
+	return b.String()
+}
+
+// quoteString returns the read syntax of s. Like prin1 it only escapes the
+// double quote and the backslash.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// List is a chain of cons cells. The cdr of the last cell is Tail, which is
+// nil for a proper list. A single cons cell (a . b) is a List with one item
+// and a Tail. The empty list () is the same as the symbol nil.
+//
+// The reader keeps the structure as written, so (a . (b c)) is read as a
+// List with a List Tail. It is printed as (a b c) like prin1 does.
+type List struct {
+	span
+	Items []Value
+	Tail  Value
+
+	close Position
+}
+
+// Close returns the position of the closing parenthesis
+func (l List) Close() Position {
+	return l.close
+}
+
+// IsProper reports whether the list ends in nil
+func (l List) IsProper() bool {
+	return l.Tail == nil || IsNil(l.Tail)
+}
+
+// Car returns the first element of the list or nil for the empty list
+func (l List) Car() Value {
+	if len(l.Items) == 0 {
+		return Symbol{Name: "nil"}
+	}
+	return l.Items[0]
+}
+
+// Cdr returns the list without its first element. For a cons cell it
+// returns the Tail.
+func (l List) Cdr() Value {
+	switch {
+	case len(l.Items) == 1 && l.Tail != nil:
+		return l.Tail
+	case len(l.Items) <= 1:
+		return Symbol{Name: "nil"}
+	}
+	rest := l
+	rest.Items = l.Items[1:]
+	rest.start = l.Items[1].Start()
+	return rest
+}
+
+func (l List) String() string {
+	if prefix, quoted, ok := quotedForm(l); ok {
+		return prefix + quoted.String()
+	}
+	items, tail := l.flatten()
+	var b strings.Builder
+	b.WriteByte('(')
+	writeItems(&b, items)
+	if tail != nil {
+		b.WriteString(" . ")
+		b.WriteString(tail.String())
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// flatten returns all elements of the list including those of list tails
+// and the final tail, which is nil for a proper list
+func (l List) flatten() ([]Value, Value) {
+	items := l.Items
+	tail := l.Tail
+	for {
+		next, ok := tail.(List)
+		if !ok || len(next.Items) == 0 {
+			break
+		}
+		items = append(items[:len(items):len(items)], next.Items...)
+		tail = next.Tail
+	}
+	if tail != nil && IsNil(tail) {
+		tail = nil
+	}
+	return items, tail
+}
+
+// quotedForm returns the prefix ' for (quote x) and #' for (function x)
+// together with x
+func quotedForm(l List) (string, Value, bool) {
+	items, tail := l.flatten()
+	if len(items) != 2 || tail != nil {
+		return "", nil, false
+	}
+	head, ok := items[0].(Symbol)
+	if !ok {
+		return "", nil, false
+	}
+	switch head.Name {
+	case "quote":
+		return "'", items[1], true
+	case "function":
+		return "#'", items[1], true
+	}
+	return "", nil, false
+}
+
+type Vector struct {
+	span
+	Items []Value
+}
+
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	writeItems(&b, v.Items)
+	b.WriteByte(']')
+	return b.String()
+}
+
+// ByteCode is a byte-code function object #[...]
+type ByteCode struct {
+	span
+	Items []Value
+}
+
+func (c ByteCode) String() string {
+	return "#" + Vector{Items: c.Items}.String()
+}
+
+// Record is a record or hash table #s(...)
+type Record struct {
+	span
+	Items []Value
+}
+
+func (r Record) String() string {
+	var b strings.Builder
+	b.WriteString("#s(")
+	writeItems(&b, r.Items)
+	b.WriteByte(')')
+	return b.String()
+}
+
+func writeItems(b *strings.Builder, items []Value) {
+	for i, item := range items {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(item.String())
+	}
+}
+
+// IsNil reports whether v is the symbol nil or the empty list
+func IsNil(v Value) bool {
+	switch v := v.(type) {
+	case Symbol:
+		return v.Name == "nil"
+	case List:
+		return len(v.Items) == 0 && v.Tail == nil
+	}
+	return false
+}
+
+// EscapeSymbol returns the read syntax of the symbol name. Like prin1 it
+// escapes the characters that are not symbol constituents and the first
+// character of names that would otherwise be read as a number or other
+// syntax. The symbol with the empty name is written as ##.
+func EscapeSymbol(name string) string {
+	if name == "" {
+		return "##"
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if r == '\\' || !isSymbolRune(r) || (i == 0 && !isSymbolStart(r)) {
+			b.WriteRune('\\')
+		} else if i == 0 && (name == "." || isNumber(name)) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package sexp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValueString(t *testing.T) {
+	testcases := map[string]struct {
+		value  Value
+		wanted string
+	}{
+		"symbol": {
+			value:  Symbol{Name: "foo bar"},
+			wanted: `foo\ bar`,
+		},
+		"number symbol": {
+			value:  Symbol{Name: "12"},
+			wanted: `\12`,
+		},
+		"empty symbol": {
+			value:  Symbol{Name: ""},
+			wanted: "##",
+		},
+		"string": {
+			value:  String{Value: "say \"hi\"\\\n"},
+			wanted: "\"say \\\"hi\\\"\\\\\n\"",
+		},
+		"literal": {
+			value:  String{Value: "\x01", Literal: `"\C-a"`},
+			wanted: `"\C-a"`,
+		},
+		"cons": {
+			value:  List{Items: []Value{Symbol{Name: "a"}}, Tail: Number{Text: "1"}},
+			wanted: "(a . 1)",
+		},
+		"nil tail": {
+			value:  List{Items: []Value{Symbol{Name: "a"}}, Tail: Symbol{Name: "nil"}},
+			wanted: "(a)",
+		},
+		"quote": {
+			value:  List{Items: []Value{Symbol{Name: "quote"}, Symbol{Name: "a"}}},
+			wanted: "'a",
+		},
+		"record": {
+			value:  Record{Items: []Value{Symbol{Name: "quote"}, Symbol{Name: "a"}}},
+			wanted: "#s(quote a)",
+		},
+	}
+	for name, tc := range testcases {
+		if got := tc.value.String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+}
+
+func TestListCdr(t *testing.T) {
+	a, b, c := Symbol{Name: "a"}, Symbol{Name: "b"}, Symbol{Name: "c"}
+	testcases := map[string]struct {
+		list   List
+		wanted string
+	}{
+		"empty":  {list: List{}, wanted: "nil"},
+		"single": {list: List{Items: []Value{a}}, wanted: "nil"},
+		"cons":   {list: List{Items: []Value{a}, Tail: b}, wanted: "b"},
+		"proper": {list: List{Items: []Value{a, b, c}}, wanted: "(b c)"},
+		"dotted": {list: List{Items: []Value{a, b}, Tail: c}, wanted: "(b . c)"},
+	}
+	for name, tc := range testcases {
+		if got := tc.list.Cdr().String(); got != tc.wanted {
+			t.Errorf("%s: Got '%s' but wanted '%s'", name, got, tc.wanted)
+		}
+	}
+	if !IsNil(List{}) || !IsNil(Symbol{Name: "nil"}) || IsNil(a) {
+		t.Errorf("IsNil doesn't match nil and () only")
+	}
+}
+
+func TestNumber(t *testing.T) {
+	testcases := map[string]struct {
+		text          string
+		wantedInteger bool
+		wantedFloat   float64
+	}{
+		"integer":  {text: "-12", wantedInteger: true, wantedFloat: -12},
+		"float":    {text: "1.5e3", wantedFloat: 1500},
+		"infinity": {text: "-1.0e+INF", wantedFloat: math.Inf(-1)},
+	}
+	for name, tc := range testcases {
+		n := Number{Text: tc.text}
+		if got := n.IsInteger(); got != tc.wantedInteger {
+			t.Errorf("%s: Got IsInteger %t but wanted %t", name, got, tc.wantedInteger)
+		}
+		got, err := n.Float64()
+		if err != nil || got != tc.wantedFloat {
+			t.Errorf("%s: Got %g (%v) but wanted %g", name, got, err, tc.wantedFloat)
+		}
+	}
+	if i, err := (Number{Text: "42"}).Int64(); err != nil || i != 42 {
+		t.Errorf("Got %d (%v) but wanted 42", i, err)
+	}
+	if f, err := (Number{Text: "0.0e+NaN"}).Float64(); err != nil || !math.IsNaN(f) {
+		t.Errorf("Got %g (%v) but wanted NaN", f, err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	svgWidth       = 800
+	svgMargin      = 20
+	svgTitleHeight = 40
+	svgBarHeight   = 22
+	svgLabelWidth  = 260
+	svgBarMaxWidth = 380
+	svgDonutRadius = 120
+	svgDonutWidth  = 60
+	svgLegendRow   = 20
+)
+
+var svgPalette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// rowLabel joins the text columns of row, e.g. "org-mode / next-line"
+func rowLabel(table Table, row Row) string {
+	var parts []string
+	for _, column := range table.Columns {
+		if s, ok := row.value(column).(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// writeBars draws a horizontal bar per row and returns the height used
+func writeBars(b *bytes.Buffer, table Table, y int, top int) int {
+	rows := table.Rows
+	if top > 0 && len(rows) > top {
+		rows = rows[:top]
+	}
+	var max uint64
+	for _, row := range rows {
+		if row.Count > max {
+			max = row.Count
+		}
+	}
+	percentColumn := barColumn(table)
+
+	fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\" class=\"title\">%s</text>\n", svgMargin, y+24, html.EscapeString(table.Title))
+	y += svgTitleHeight
+	for i, row := range rows {
+		width := 0.0
+		if max > 0 {
+			width = float64(row.Count) / float64(max) * svgBarMaxWidth
+		}
+		top := y + i*svgBarHeight
+		fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n",
+			svgMargin+svgLabelWidth-8, top+15, html.EscapeString(rowLabel(table, row)))
+		fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"%.1f\" height=\"%d\" fill=\"%s\"/>\n",
+			svgMargin+svgLabelWidth, top+3, width, svgBarHeight-6, svgPalette[0])
+		fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%d\">%s (%s)</text>\n",
+			float64(svgMargin+svgLabelWidth)+width+6, top+15,
+			formatCount(row.Count), formatCell(row.value(percentColumn)))
+	}
+	return svgTitleHeight + len(rows)*svgBarHeight + svgMargin
+}
+
+// writeDonut draws the share of every row as a ring segment. Rows beyond
+// the number of colors are combined into one "(other)" segment.
+func writeDonut(b *bytes.Buffer, table Table, y int) int {
+	type slice struct {
+		label string
+		count uint64
+	}
+	var slices []slice
+	var total uint64
+	for i, row := range table.Rows {
+		total += row.Count
+		if i < len(svgPalette)-1 || len(table.Rows) == len(svgPalette) {
+			slices = append(slices, slice{rowLabel(table, row), row.Count})
+		} else if i == len(svgPalette)-1 {
+			slices = append(slices, slice{"(other)", row.Count})
+		} else {
+			slices[len(slices)-1].count += row.Count
+		}
+	}
+
+	fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\" class=\"title\">%s</text>\n", svgMargin, y+24, html.EscapeString(table.Title))
+	y += svgTitleHeight
+
+	radius := float64(svgDonutRadius - svgDonutWidth/2)
+	circumference := 2 * math.Pi * radius
+	cx := svgMargin + svgDonutRadius
+	cy := y + svgDonutRadius
+	var offset float64
+	for i, s := range slices {
+		fraction := 0.0
+		if total > 0 {
+			fraction = float64(s.count) / float64(total)
+		}
+		fmt.Fprintf(b, "<circle cx=\"%d\" cy=\"%d\" r=\"%.1f\" fill=\"none\" stroke=\"%s\" stroke-width=\"%d\" "+
+			"stroke-dasharray=\"%.2f %.2f\" stroke-dashoffset=\"%.2f\" transform=\"rotate(-90 %d %d)\"/>\n",
+			cx, cy, radius, svgPalette[i], svgDonutWidth,
+			fraction*circumference, circumference, -offset*circumference, cx, cy)
+		offset += fraction
+
+		legendX := svgMargin + 2*svgDonutRadius + 40
+		legendY := y + i*svgLegendRow
+		fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"12\" height=\"12\" fill=\"%s\"/>\n", legendX, legendY+3, svgPalette[i])
+		fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\">%s %.2f%%</text>\n", legendX+18, legendY+14,
+			html.EscapeString(s.label), 100*fraction)
+	}
+
+	height := 2 * svgDonutRadius
+	if legend := len(slices) * svgLegendRow; legend > height {
+		height = legend
+	}
+	return svgTitleHeight + height + svgMargin
+}
+
+// writeSVG renders a standalone SVG document with a donut chart for the
+// mode table and bar charts limited to top rows for all other tables
+func writeSVG(w io.Writer, tables []Table, top int) error {
+	var body bytes.Buffer
+	height := svgMargin
+	for _, table := range tables {
+		if table.Name == "modes" {
+			height += writeDonut(&body, table, height)
+		} else {
+			height += writeBars(&body, table, height, top)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">
+<style>.title { font-size: 16px; font-weight: bold; }</style>
+<rect width="100%%" height="100%%" fill="white"/>
+%s</svg>
+`, svgWidth, height, svgWidth, height, body.String())
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+// countElements checks that svg is well-formed XML and counts its elements
+// by name
+func countElements(svg []byte) (map[string]int, error) {
+	counts := make(map[string]int)
+	decoder := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+}
+
+func TestSVGReport(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("(")
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&content, "((mode-%d . command-%d) . %d) ", i, i, i)
+	}
+	content.WriteString(")")
+	stats, err := keyfreq.Parse(strings.NewReader(content.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testcases := map[string]struct {
+		opts          Opts
+		wantedRects   int
+		wantedCircles int
+		wantedText    []string
+	}{
+		"functions": {
+			opts:        Opts{mode: FUNCTIONS, chartTop: 5},
+			wantedRects: 1 + 5,
+			wantedText:  []string{"Funcs", "command-12", "command-8"},
+		},
+		"modes": {
+			opts:          Opts{mode: MODES},
+			wantedRects:   1 + len(svgPalette),
+			wantedCircles: len(svgPalette),
+			wantedText:    []string{"Modes", "mode-12", "(other)"},
+		},
+		"all": {
+			opts:          Opts{mode: ALL, chartTop: 3},
+			wantedRects:   1 + 3 + len(svgPalette),
+			wantedCircles: len(svgPalette),
+			wantedText:    []string{"Funcs", "Modes"},
+		},
+	}
+	for name, tc := range testcases {
+		var b bytes.Buffer
+		tables := buildTables([]input{{stats: stats}}, tc.opts)
+		if err := writeSVG(&b, tables, tc.opts.chartTop); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		counts, err := countElements(b.Bytes())
+		if err != nil {
+			t.Errorf("%s: SVG is not well-formed: %s", name, err)
+			continue
+		}
+		if counts["rect"] != tc.wantedRects {
+			t.Errorf("%s: Got %d rects but wanted %d", name, counts["rect"], tc.wantedRects)
+		}
+		if counts["circle"] != tc.wantedCircles {
+			t.Errorf("%s: Got %d circles but wanted %d", name, counts["circle"], tc.wantedCircles)
+		}
+		for _, text := range tc.wantedText {
+			if !bytes.Contains(b.Bytes(), []byte(">"+text)) {
+				t.Errorf("%s: SVG does not contain text '%s'", name, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultWidth = 80
+
+// terminalWidth returns the width of the terminal on stdout. If stdout is
+// not a terminal it falls back to $COLUMNS or defaultWidth.
+func terminalWidth() int {
+	if width := ttyWidth(); width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultWidth
+}
+
+// formatCount formats n with thousands separators
+func formatCount(n uint64) string {
+	digits := strconv.FormatUint(n, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case uint64:
+		return formatCount(v)
+	case int64:
+		if v < 0 {
+			return "-" + formatCount(uint64(-v))
+		}
+		return "+" + formatCount(uint64(v))
+	case float64:
+		return fmt.Sprintf("%.2f%%", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isNumeric(column string) bool {
+	switch column {
+	case colCount, colPercent, colModePercent, colOld, colNew, colChange, colRelative:
+		return true
+	}
+	return false
+}
+
+// barColumn returns the percentage the bars of table are drawn for
+func barColumn(table Table) string {
+	for _, column := range table.Columns {
+		if column == colModePercent {
+			return colModePercent
+		}
+	}
+	return colPercent
+}
+
+// writeTable renders the tables as aligned text. With bars a bar chart
+// column fills the space up to width.
+func writeTable(w io.Writer, tables []Table, bars bool, width int) error {
+	for i, table := range tables {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if len(tables) > 1 {
+			if _, err := fmt.Fprintf(w, "%s\n%s\n", table.Title, strings.Repeat("-", len(table.Title))); err != nil {
+				return err
+			}
+		}
+		if err := writeAlignedTable(w, table, bars, width); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAlignedTable(w io.Writer, table Table, bars bool, width int) error {
+	cells := make([][]string, len(table.Rows)+1)
+	widths := make([]int, len(table.Columns))
+	cells[0] = table.Columns
+	for i, row := range table.Rows {
+		cells[i+1] = make([]string, len(table.Columns))
+		for j, column := range table.Columns {
+			cells[i+1][j] = formatCell(row.value(column))
+		}
+	}
+	for _, line := range cells {
+		for j, cell := range line {
+			if n := utf8.RuneCountInString(cell); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+
+	barWidth := 0
+	var maxPercent float64
+	if bars {
+		barWidth = width
+		for _, cw := range widths {
+			barWidth -= cw + 2
+		}
+		column := barColumn(table)
+		for _, row := range table.Rows {
+			if percent, ok := row.value(column).(float64); ok {
+				maxPercent = math.Max(maxPercent, percent)
+			}
+		}
+	}
+
+	for i, line := range cells {
+		fields := make([]string, len(line))
+		for j, cell := range line {
+			padding := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			if isNumeric(table.Columns[j]) {
+				fields[j] = padding + cell
+			} else {
+				fields[j] = cell + padding
+			}
+		}
+		text := strings.Join(fields, "  ")
+		if barWidth > 0 && i > 0 && maxPercent > 0 {
+			percent, _ := table.Rows[i-1].value(barColumn(table)).(float64)
+			length := int(math.Round(percent / maxPercent * float64(barWidth)))
+			text += "  " + strings.Repeat("#", length)
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(text, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
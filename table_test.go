@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/native-human/go-keyfreq/keyfreq"
+)
+
+func TestFormatCount(t *testing.T) {
+	testcases := map[uint64]string{
+		0:       "0",
+		12:      "12",
+		123:     "123",
+		1234:    "1,234",
+		123456:  "123,456",
+		1234567: "1,234,567",
+	}
+	for input, wanted := range testcases {
+		if got := formatCount(input); got != wanted {
+			t.Errorf("%d: Got '%s' but wanted '%s'", input, got, wanted)
+		}
+	}
+}
+
+func TestTableReport(t *testing.T) {
+	content := `(((org-mode . next-line) . 3000)
+ ((org-mode . org-cycle) . 1000))`
+	testcases := map[string]struct {
+		opts   Opts
+		width  int
+		wanted string
+	}{
+		"functions": {
+			opts: Opts{mode: FUNCTIONS},
+			wanted: "" +
+				"key        count  percent\n" +
+				"next-line  3,000   75.00%\n" +
+				"org-cycle  1,000   25.00%\n",
+		},
+		"bars": {
+			opts:  Opts{mode: FUNCTIONS, bars: true},
+			width: 40,
+			wanted: "" +
+				"key        count  percent\n" +
+				"next-line  3,000   75.00%  #############\n" +
+				"org-cycle  1,000   25.00%  ####\n",
+		},
+		"too narrow for bars": {
+			opts:  Opts{mode: FUNCTIONS, bars: true},
+			width: 20,
+			wanted: "" +
+				"key        count  percent\n" +
+				"next-line  3,000   75.00%\n" +
+				"org-cycle  1,000   25.00%\n",
+		},
+		"all": {
+			opts: Opts{mode: ALL},
+			wanted: "" +
+				"Funcs\n" +
+				"-----\n" +
+				"key        count  percent\n" +
+				"next-line  3,000   75.00%\n" +
+				"org-cycle  1,000   25.00%\n" +
+				"\n" +
+				"Modes\n" +
+				"-----\n" +
+				"key       count  percent\n" +
+				"org-mode  4,000  100.00%\n",
+		},
+	}
+	stats, err := keyfreq.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, tc := range testcases {
+		var b bytes.Buffer
+		tables := buildTables([]input{{stats: stats}}, tc.opts)
+		if err := writeTable(&b, tables, tc.opts.bars, tc.width); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if got := b.String(); got != tc.wanted {
+			t.Errorf("%s: Got\n%s\nbut wanted\n%s", name, got, tc.wanted)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package main
+
+// ttyWidth returns 0 as the terminal size is not queried on this platform
+func ttyWidth() int {
+	return 0
+}